@@ -0,0 +1,40 @@
+package workgroup
+
+import "context"
+
+// DoTasksAny starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes each input as a task until one of them succeeds,
+// returning the input and output of the first successful task.
+// Once a task succeeds, no further tasks are dispatched,
+// and the context passed to the tasks that are still running is canceled
+// with Stop as its cause (see DoContext);
+// DoTasksAny returns once they have finished.
+// If every task fails, DoTasksAny returns the zero Input, the zero Output,
+// and the task errors joined into a multierror.
+// If a task panics during execution,
+// the panic will be caught and returned as an error halting further execution.
+func DoTasksAny[Input, Output any](n int, items []Input, task func(context.Context, Input) (Output, error), opts ...Option) (Input, Output, error) {
+	var (
+		won  bool
+		win  Input
+		out  Output
+		errs []error
+	)
+	err := DoContext(context.Background(), n, task, func(in Input, o Output, err error) ([]Input, error) {
+		if err != nil {
+			errs = append(errs, err)
+			return nil, nil
+		}
+		won, win, out = true, in, o
+		return nil, Stop
+	}, items, opts...)
+	if err != nil {
+		var in Input
+		var o Output
+		return in, o, err
+	}
+	if !won {
+		return win, out, newConfig(opts).join(errs)
+	}
+	return win, out, nil
+}
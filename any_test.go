@@ -0,0 +1,48 @@
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksAny(t *testing.T) {
+	mirrors := []string{"slow", "broken", "fast"}
+	canceled := make(chan error, 1)
+	mirror, body, err := workgroup.DoTasksAny(3, mirrors,
+		func(ctx context.Context, mirror string) (string, error) {
+			switch mirror {
+			case "slow":
+				<-ctx.Done()
+				canceled <- context.Cause(ctx)
+				return "", ctx.Err()
+			case "broken":
+				return "", errors.New("connection refused")
+			}
+			time.Sleep(10 * time.Millisecond)
+			return "hello from " + mirror, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mirror != "fast" || body != "hello from fast" {
+		t.Fatal(mirror, body)
+	}
+	if cause := <-canceled; cause != workgroup.Stop {
+		t.Fatal(cause)
+	}
+
+	mirror, body, err = workgroup.DoTasksAny(2, []string{"a", "b"},
+		func(ctx context.Context, mirror string) (string, error) {
+			return "", errors.New(mirror + " failed")
+		})
+	if mirror != "" || body != "" || err == nil {
+		t.Fatal(mirror, body, err)
+	}
+	if err.Error() != "a failed\nb failed" && err.Error() != "b failed\na failed" {
+		t.Fatal(err)
+	}
+}
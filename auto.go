@@ -0,0 +1,158 @@
+package workgroup
+
+import (
+	"context"
+	"runtime"
+)
+
+// DoTasksAuto is like DoTasks, but instead of being told how many workers to use,
+// it searches for the count that gets through the most tasks per second.
+// It starts at GOMAXPROCS workers (or n, if that is fewer)
+// and hill-climbs from there:
+// after each window of completed tasks it measures the throughput
+// and moves the count up or down, never below 1 or above n,
+// for as long as each move improves throughput by at least 5%.
+// Once a move in either direction stops paying off,
+// it settles on the best count found for the rest of the run.
+// If n is Unlimited, the search goes up to one worker per input,
+// and otherwise if n < 1, up to four times GOMAXPROCS.
+// See WithTuningReport to follow the search.
+// DoTasksAuto adjusts the count through a Controller of its own,
+// so WithController has no effect on it.
+//
+// The search costs some throughput while it runs,
+// and because it measures the live workload,
+// it suits batches that are large compared to the number of workers
+// and whose tasks are broadly alike.
+// Errors returned by a task do not halt execution,
+// but are joined into a multierror return value.
+// If a task panics during execution,
+// the panic will be caught and returned as an error halting further execution.
+func DoTasksAuto[Input any](n int, items []Input, task func(Input) error, opts ...Option) error {
+	cfg := newConfig(opts)
+	if n == Unlimited {
+		n = cfg.poolSize(context.Background(), n, len(items))
+	}
+	if n < 1 {
+		n = 4 * runtime.GOMAXPROCS(0)
+	}
+	cfg.controller = NewController()
+	t := &tuner{max: n, workers: runtime.GOMAXPROCS(0), dir: 1}
+	if t.workers > n {
+		t.workers = n
+	}
+	cfg.controller.SetWorkers(t.workers)
+	start := cfg.clock.Now()
+	completed, warmup := 0, 0
+	var errs []error
+	err := do(context.Background(), cfg, n, func(in Input) (void, error) {
+		return void{}, task(in)
+	}, func(_ Input, _ void, err error) ([]Input, error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if t.settled {
+			return nil, nil
+		}
+		if warmup > 0 {
+			if warmup--; warmup == 0 {
+				start = cfg.clock.Now()
+			}
+			return nil, nil
+		}
+		if completed++; completed < t.window() {
+			return nil, nil
+		}
+		throughput := float64(completed) / cfg.clock.Now().Sub(start).Seconds()
+		if cfg.tuningReport != nil {
+			cfg.tuningReport(t.workers, throughput)
+		}
+		// let the tasks started under the old count finish
+		// before measuring the new one
+		warmup = t.workers
+		cfg.controller.SetWorkers(t.next(throughput))
+		completed = 0
+		return nil, nil
+	}, items)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return cfg.join(errs)
+}
+
+// WithTuningReport has DoTasksAuto call report
+// each time it measures the throughput of a worker count,
+// with the count and the tasks completed per second.
+// It is called serially, between tasks.
+func WithTuningReport(report func(workers int, throughput float64)) Option {
+	return func(cfg *config) {
+		cfg.tuningReport = report
+	}
+}
+
+// tuner is the hill-climbing search of DoTasksAuto.
+type tuner struct {
+	max      int
+	workers  int
+	dir      int
+	best     int
+	bestRate float64
+	improved bool // whether any move has paid off yet
+	reversed bool
+	settled  bool
+}
+
+// window is how many tasks to complete before measuring the current count.
+func (t *tuner) window() int {
+	if w := 4 * t.workers; w > 16 {
+		return w
+	}
+	return 16
+}
+
+// next records the throughput of the current count
+// and returns the count to try next.
+func (t *tuner) next(throughput float64) int {
+	switch {
+	case t.best == 0:
+		t.best, t.bestRate = t.workers, throughput
+	case throughput >= 1.05*t.bestRate:
+		t.best, t.bestRate = t.workers, throughput
+		t.improved = true
+	case t.improved || t.reversed:
+		t.settled = true
+	default:
+		t.dir, t.reversed = -t.dir, true
+	}
+	if !t.settled {
+		t.workers = t.step()
+		if t.workers == t.best && !t.reversed && !t.improved {
+			// nowhere to go this way, so try the other
+			t.dir, t.reversed = -t.dir, true
+			t.workers = t.step()
+		}
+		if t.workers == t.best {
+			t.settled = true
+		}
+	}
+	if t.settled {
+		t.workers = t.best
+	}
+	return t.workers
+}
+
+// step returns the count one step from the best count in the current direction.
+func (t *tuner) step() int {
+	d := t.best / 4
+	if d < 1 {
+		d = 1
+	}
+	w := t.best + t.dir*d
+	if w < 1 {
+		w = 1
+	}
+	if w > t.max {
+		w = t.max
+	}
+	return w
+}
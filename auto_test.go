@@ -0,0 +1,40 @@
+package workgroup_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksAuto(t *testing.T) {
+	// tasks slow down sharply once more than 4 run at once,
+	// so 4 workers get through the most tasks per second
+	var running atomic.Int64
+	task := func(int) error {
+		k := running.Add(1)
+		defer running.Add(-1)
+		d := 2 * time.Millisecond
+		if k > 4 {
+			d = d * time.Duration(k*k*k) / 64
+		}
+		time.Sleep(d)
+		return nil
+	}
+	var tried []int
+	best, bestRate := 0, 0.0
+	err := workgroup.DoTasksAuto(12, make([]int, 600), task,
+		workgroup.WithTuningReport(func(workers int, throughput float64) {
+			tried = append(tried, workers)
+			if throughput > bestRate {
+				best, bestRate = workers, throughput
+			}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tried) < 2 || best < 3 || best > 5 {
+		t.Fatal(best, tried)
+	}
+}
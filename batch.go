@@ -0,0 +1,70 @@
+package workgroup
+
+import (
+	"context"
+	"time"
+)
+
+// DoBatchesAdaptive starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes items in consecutive batches, passing each batch to task.
+// The batch size adapts to the time taken by the batches so far,
+// aiming for each batch to take about target to run:
+// the first batches hold a single input,
+// and each later batch is sized from the time per input of the last batch to finish,
+// growing or shrinking by at most a factor of two at a time.
+// Sizing is a heuristic suited to work like bulk database writes
+// whose cost per input varies;
+// it does not guarantee how long any batch takes.
+// Errors returned by task do not halt execution,
+// but are joined into a multierror return value.
+// If task panics during execution,
+// the panic will be caught and returned as an error halting further execution.
+func DoBatchesAdaptive[Input any](n int, target time.Duration, items []Input, task func([]Input) error, opts ...Option) error {
+	type batch struct{ lo, hi int }
+	cfg := newConfig(opts)
+	n = cfg.poolSize(context.Background(), n, len(items))
+	next, size := 0, 1
+	take := func() []batch {
+		if next >= len(items) {
+			return nil
+		}
+		hi := next + size
+		if hi > len(items) {
+			hi = len(items)
+		}
+		b := batch{next, hi}
+		next = hi
+		return []batch{b}
+	}
+	var initial []batch
+	for i := 0; i < n; i++ {
+		initial = append(initial, take()...)
+	}
+	var errs []error
+	err := DoWith(n, func(b batch) (time.Duration, error) {
+		start := cfg.clock.Now()
+		err := task(items[b.lo:b.hi:b.hi])
+		return cfg.clock.Now().Sub(start), err
+	}, func(b batch, elapsed time.Duration, err error) ([]batch, error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+		perInput := elapsed / time.Duration(b.hi-b.lo)
+		want := 2 * size
+		if perInput > 0 && int64(target/perInput) < int64(want) {
+			want = int(target / perInput)
+		}
+		if want < size/2 {
+			want = size / 2
+		}
+		if want < 1 {
+			want = 1
+		}
+		size = want
+		return take(), nil
+	}, initial, opts...)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return cfg.join(errs)
+}
@@ -0,0 +1,48 @@
+package workgroup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoBatchesAdaptive(t *testing.T) {
+	// the first half of the inputs are cheap and the second half are slow
+	costs := make([]time.Duration, 200)
+	for i := 100; i < len(costs); i++ {
+		costs[i] = 5 * time.Millisecond
+	}
+	var sizes []int
+	var fastMax, slowLast int
+	err := workgroup.DoBatchesAdaptive(1, 20*time.Millisecond, costs,
+		func(batch []time.Duration) error {
+			var total time.Duration
+			for _, d := range batch {
+				total += d
+			}
+			time.Sleep(total)
+			sizes = append(sizes, len(batch))
+			if batch[0] == 0 && len(batch) > fastMax {
+				fastMax = len(batch)
+			}
+			if batch[0] != 0 {
+				slowLast = len(batch)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, n := range sizes {
+		total += n
+	}
+	if total != len(costs) {
+		t.Fatal(sizes)
+	}
+	// batches grow while inputs are cheap and shrink once they get slow
+	if fastMax < 16 || slowLast > 8 {
+		t.Fatal(sizes)
+	}
+}
@@ -0,0 +1,81 @@
+package workgroup
+
+import "context"
+
+// Budget caps the total number of tasks running at once
+// across any number of concurrent calls.
+// Each call draws from the Budget through a Limiter returned by Sub.
+type Budget struct {
+	sem chan void
+}
+
+// NewBudget returns a Budget that allows at most total tasks to run at once.
+// It panics if total is less than 1.
+func NewBudget(total int) *Budget {
+	if total < 1 {
+		panic("workgroup: NewBudget called with total < 1")
+	}
+	return &Budget{sem: make(chan void, total)}
+}
+
+// Sub returns a Limiter that allows at most k tasks to run at once
+// while still counting each of them against b.
+// Use a separate Limiter for each call that should be capped on its own.
+// It panics if k is less than 1.
+func (b *Budget) Sub(k int) Limiter {
+	if k < 1 {
+		panic("workgroup: Budget.Sub called with k < 1")
+	}
+	return Limiter{
+		local:  make(chan void, k),
+		global: b.sem,
+	}
+}
+
+// Limiter bounds how many tasks may run at once.
+// Pass it to a Do-family function with WithLimiter.
+// The zero Limiter imposes no limit.
+type Limiter struct {
+	local, global chan void
+}
+
+// Acquire blocks until a task may run or ctx is done.
+// If ctx is done first, Acquire returns ctx.Err()
+// and the task must not run or call Release.
+func (l Limiter) Acquire(ctx context.Context) error {
+	if l.local == nil {
+		return nil
+	}
+	select {
+	case l.local <- void{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case l.global <- void{}:
+	case <-ctx.Done():
+		<-l.local
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Release marks a task started by a successful Acquire as finished.
+func (l Limiter) Release() {
+	if l.local == nil {
+		return
+	}
+	<-l.global
+	<-l.local
+}
+
+// WithLimiter makes each worker acquire l before running a task
+// and release it once the task returns.
+// No more workers are started than l allows to run at once.
+// If the run is canceled while a worker waits to acquire l,
+// the task is not run and the cancellation error is its result.
+func WithLimiter(l Limiter) Option {
+	return func(cfg *config) {
+		cfg.limiter = &l
+	}
+}
@@ -0,0 +1,82 @@
+package workgroup_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestBudget(t *testing.T) {
+	budget := workgroup.NewBudget(3)
+	var global gauge
+	calls := make([]gauge, 4)
+	var wg sync.WaitGroup
+	for i := range calls {
+		wg.Add(1)
+		go func(call *gauge) {
+			defer wg.Done()
+			err := workgroup.DoTasks(4, make([]int, 10), func(int) error {
+				global.enter()
+				call.enter()
+				time.Sleep(5 * time.Millisecond)
+				call.exit()
+				global.exit()
+				return nil
+			}, workgroup.WithLimiter(budget.Sub(2)))
+			if err != nil {
+				t.Error(err)
+			}
+		}(&calls[i])
+	}
+	wg.Wait()
+	if peak := global.peak.Load(); peak > 3 || peak < 2 {
+		t.Fatal(peak)
+	}
+	for i := range calls {
+		if peak := calls[i].peak.Load(); peak > 2 {
+			t.Fatal(i, peak)
+		}
+	}
+}
+
+func TestLimiter_Acquire(t *testing.T) {
+	l := workgroup.NewBudget(1).Sub(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := l.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := l.Acquire(ctx); err != context.Canceled {
+		t.Fatal(err)
+	}
+	l.Release()
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	l.Release()
+
+	var zero workgroup.Limiter
+	if err := zero.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	zero.Release()
+}
+
+func TestBudget_invalid(t *testing.T) {
+	for _, f := range []func(){
+		func() { workgroup.NewBudget(0) },
+		func() { workgroup.NewBudget(1).Sub(-1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
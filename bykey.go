@@ -0,0 +1,46 @@
+package workgroup
+
+import (
+	"context"
+	"errors"
+)
+
+// DoTasksByKey starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes each input as a task,
+// routing every input with the same key to the same worker.
+// Tasks for a given key therefore run one at a time, in the order of items,
+// so per-key state can be updated without locks.
+// Keys are assigned to workers in turn as they are first seen,
+// which spreads keys evenly but not necessarily work:
+// a worker with a hot key may finish well after the others.
+// Errors returned by a task do not halt execution,
+// but are joined into a multierror return value.
+// If a task panics during execution,
+// the panic will be caught and returned as an error halting further execution.
+func DoTasksByKey[Input any, K comparable](n int, key func(Input) K, items []Input, task func(Input) error, opts ...Option) error {
+	n = newConfig(opts).poolSize(context.Background(), n, len(items))
+	buckets := make([][]Input, n)
+	owner := make(map[K]int)
+	for _, in := range items {
+		k := key(in)
+		w, ok := owner[k]
+		if !ok {
+			w = len(owner) % n
+			owner[k] = w
+		}
+		buckets[w] = append(buckets[w], in)
+	}
+	if len(owner) < n {
+		n = len(owner)
+		buckets = buckets[:n]
+	}
+	return DoTasks(n, buckets, func(bucket []Input) error {
+		var errs []error
+		for _, in := range bucket {
+			if err := task(in); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}, opts...)
+}
@@ -0,0 +1,56 @@
+package workgroup_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksByKey(t *testing.T) {
+	type event struct {
+		user string
+		n    int
+	}
+	var events []event
+	totals := map[string]*int{}
+	for u := 0; u < 10; u++ {
+		user := fmt.Sprint("user", u)
+		totals[user] = new(int)
+		for n := 0; n < 20; n++ {
+			events = append(events, event{user, n})
+		}
+	}
+	var g gauge
+	lastSeen := map[string]*int{}
+	for user := range totals {
+		lastSeen[user] = new(int)
+		*lastSeen[user] = -1
+	}
+	err := workgroup.DoTasksByKey(4, func(e event) string { return e.user }, events,
+		func(e event) error {
+			g.enter()
+			defer g.exit()
+			// unsynchronized per-key state: the race detector flags
+			// any two tasks for the same user running at once
+			*totals[e.user] += e.n
+			if *lastSeen[e.user] != e.n-1 {
+				return fmt.Errorf("%s: got %d after %d", e.user, e.n, *lastSeen[e.user])
+			}
+			*lastSeen[e.user] = e.n
+			time.Sleep(100 * time.Microsecond)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for user, total := range totals {
+		if *total != 190 {
+			t.Fatal(user, *total)
+		}
+	}
+	if peak := g.peak.Load(); peak < 2 || peak > 4 {
+		t.Fatal(peak)
+	}
+}
@@ -0,0 +1,89 @@
+package workgroup
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// TaskCanceler cancels the contexts of individual running tasks,
+// picked out by a key derived from their input,
+// such as a crawl task whose result has been superseded,
+// while the rest of the run carries on.
+// Create one with NewTaskCanceler, pass it to a run with WithTaskCanceler,
+// and call CancelTask from the manager or from any other goroutine.
+// It only affects runs that pass their tasks a context,
+// such as DoContext and DoTasksContext.
+type TaskCanceler[K comparable] struct {
+	key     func(in any) K
+	inType  reflect.Type
+	mu      sync.Mutex
+	running map[K][]*context.CancelFunc
+}
+
+// NewTaskCanceler returns a TaskCanceler that identifies tasks by key(input).
+// The Input type must match the Input type of the run;
+// if it does not, the run panics before starting any tasks.
+func NewTaskCanceler[Input any, K comparable](key func(Input) K) *TaskCanceler[K] {
+	return &TaskCanceler[K]{
+		key:     func(in any) K { return key(in.(Input)) },
+		inType:  reflect.TypeOf((*Input)(nil)).Elem(),
+		running: make(map[K][]*context.CancelFunc),
+	}
+}
+
+// WithTaskCanceler lets c cancel the running tasks of the run.
+func WithTaskCanceler[K comparable](c *TaskCanceler[K]) Option {
+	return func(cfg *config) {
+		cfg.taskCanceler = c
+	}
+}
+
+// CancelTask cancels the context of every running task whose key is key
+// and reports whether there were any.
+// Queued inputs with the same key still run when their turn comes,
+// and the canceled tasks are managed as usual
+// with whatever they return, typically context.Canceled.
+func (c *TaskCanceler[K]) CancelTask(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cancel := range c.running[key] {
+		(*cancel)()
+	}
+	return len(c.running[key]) > 0
+}
+
+// track derives a cancelable context for the task for in
+// and returns it with a function to call once the task returns.
+func (c *TaskCanceler[K]) track(ctx context.Context, in any) (context.Context, func()) {
+	key := c.key(in)
+	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.running[key] = append(c.running[key], &cancel)
+	c.mu.Unlock()
+	return ctx, func() {
+		c.mu.Lock()
+		tasks := c.running[key]
+		for i := range tasks {
+			if tasks[i] == &cancel {
+				tasks = append(tasks[:i], tasks[i+1:]...)
+				break
+			}
+		}
+		if len(tasks) == 0 {
+			delete(c.running, key)
+		} else {
+			c.running[key] = tasks
+		}
+		c.mu.Unlock()
+		cancel()
+	}
+}
+
+func (c *TaskCanceler[K]) inputType() reflect.Type { return c.inType }
+
+// taskTracker is a TaskCanceler of any key type.
+type taskTracker interface {
+	track(ctx context.Context, in any) (context.Context, func())
+	inputType() reflect.Type
+}
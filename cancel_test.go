@@ -0,0 +1,49 @@
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestTaskCanceler(t *testing.T) {
+	type page struct{ URL string }
+	c := workgroup.NewTaskCanceler(func(p page) string { return p.URL })
+	var started sync.WaitGroup
+	started.Add(3)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	results := map[string]error{}
+	go func() {
+		started.Wait()
+		if c.CancelTask("/missing") {
+			t.Error("canceled a task that is not running")
+		}
+		if !c.CancelTask("/b") {
+			t.Error("did not cancel /b")
+		}
+		close(release)
+	}()
+	err := workgroup.DoTasksContext(context.Background(), 3, []page{{"/a"}, {"/b"}, {"/c"}},
+		func(ctx context.Context, p page) error {
+			started.Done()
+			select {
+			case <-ctx.Done():
+			case <-release:
+			}
+			mu.Lock()
+			results[p.URL] = ctx.Err()
+			mu.Unlock()
+			return ctx.Err()
+		}, workgroup.WithTaskCanceler(c))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results["/a"] != nil || results["/c"] != nil ||
+		!errors.Is(results["/b"], context.Canceled) {
+		t.Fatal(results)
+	}
+}
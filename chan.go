@@ -0,0 +1,206 @@
+package workgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Result is the outcome of a task for a single input.
+type Result[Input, Output any] struct {
+	In  Input
+	Out Output
+	Err error
+}
+
+// DoTasksChan starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes each input as a task,
+// sending each Result on the returned channel as the task completes.
+// The channel is closed after the last Result has been sent.
+// Callers must receive every Result or the workers will block.
+// A slow receiver throttles the workers in the same way as a slow manager
+// (see Do), so memory use stays bounded however fast the tasks are.
+// If a task panics during execution,
+// the panic will be caught and sent as the Err of a final Result
+// halting further execution.
+func DoTasksChan[Input, Output any](n int, items []Input, task Task[Input, Output], opts ...Option) <-chan Result[Input, Output] {
+	ch := make(chan Result[Input, Output])
+	go func() {
+		defer close(ch)
+		err := DoWith(n, task, func(in Input, out Output, err error) ([]Input, error) {
+			ch <- Result[Input, Output]{in, out, err}
+			return nil, nil
+		}, items, opts...)
+		if err != nil {
+			ch <- Result[Input, Output]{Err: err}
+		}
+	}()
+	return ch
+}
+
+// DrainResults receives every Result from ch until it is closed,
+// calling fn with each one in turn,
+// and returns the errors of the Results joined as a multierror.
+func DrainResults[Input, Output any](ch <-chan Result[Input, Output], fn func(Result[Input, Output])) error {
+	var errs []error
+	for r := range ch {
+		fn(r)
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DoTasksChanOrdered is like DoTasksChan,
+// but Results are sent in the same order as items
+// even though the tasks still execute concurrently.
+// A Result that completes ahead of the Results for earlier items
+// is held in memory until those have been sent.
+// To bound that memory, no new task is dispatched
+// while as many items as there are workers
+// have been dispatched without having their Results sent,
+// so a single slow task can hold up dispatch but not exhaust memory.
+// If a task panics during execution,
+// the panic will be caught and no further tasks will be started;
+// the Results held so far are sent in input order,
+// skipping the inputs that did not complete,
+// followed by a final Result with the panicking input and the panic as its Err.
+// If an option halts the run early for another reason, such as WithMaxErrors,
+// a Result is still sent for every item in order,
+// with the error that halted the run, such as ErrMaxErrors,
+// as the Err of each item whose task did not finish.
+func DoTasksChanOrdered[Input, Output any](n int, items []Input, task Task[Input, Output], opts ...Option) <-chan Result[Input, Output] {
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	ch := make(chan Result[Input, Output])
+	go func() {
+		defer close(ch)
+		cfg := newConfig(opts)
+		pending := make(map[int]Result[Input, Output])
+		cfg.backlog = func() int { return len(pending) }
+		cfg.managePanics = true
+		var (
+			perr   error
+			pindex int
+		)
+		cfg.abandoned = func(in any, err error) {
+			if perr == nil {
+				i := in.(int)
+				pending[i] = Result[Input, Output]{In: items[i], Err: err}
+			}
+		}
+		viewInputs(cfg, func(i int) Input { return items[i] })
+		next := 0
+		_ = do(context.Background(), cfg, n, func(i int) (Output, error) {
+			return task(items[i])
+		}, func(i int, out Output, err error) ([]int, error) {
+			var pe *panicError
+			if errors.As(err, &pe) {
+				perr, pindex = pe.err, i
+				return nil, pe
+			}
+			pending[i] = Result[Input, Output]{items[i], out, err}
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				ch <- r
+				next++
+			}
+			return nil, nil
+		}, indexes)
+		held := make([]int, 0, len(pending))
+		for i := range pending {
+			held = append(held, i)
+		}
+		sort.Ints(held)
+		for _, i := range held {
+			ch <- pending[i]
+		}
+		if perr != nil {
+			ch <- Result[Input, Output]{In: items[pindex], Err: perr}
+		}
+	}()
+	return ch
+}
+
+// KeyedResult is the outcome of a task,
+// identified by a key derived from its input rather than the input itself.
+type KeyedResult[K comparable, Output any] struct {
+	Key K
+	Out Output
+	Err error
+}
+
+// DoTasksChanKeyed is like DoTasksChan,
+// but each Result carries key(input) instead of the input,
+// which keeps the streamed values small when inputs are bulky.
+func DoTasksChanKeyed[Input, Output any, K comparable](n int, items []Input, key func(Input) K, task Task[Input, Output], opts ...Option) <-chan KeyedResult[K, Output] {
+	ch := make(chan KeyedResult[K, Output])
+	go func() {
+		defer close(ch)
+		err := DoWith(n, task, func(in Input, out Output, err error) ([]Input, error) {
+			ch <- KeyedResult[K, Output]{key(in), out, err}
+			return nil, nil
+		}, items, opts...)
+		if err != nil {
+			ch <- KeyedResult[K, Output]{Err: err}
+		}
+	}()
+	return ch
+}
+
+// TaskError is the failure of the task for a single input.
+type TaskError[Input any] struct {
+	In  Input
+	Err error
+}
+
+func (te TaskError[Input]) Error() string { return fmt.Sprintf("%v: %v", te.In, te.Err) }
+
+func (te TaskError[Input]) Unwrap() error { return te.Err }
+
+// DoTasksErrChan starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes each input as a task,
+// sending a TaskError on the returned channel as each task fails,
+// so that a monitor can react to failures while a long batch runs.
+// Tasks that succeed send nothing.
+// The channel is closed once every task has finished.
+// Callers must receive every TaskError or the workers will block,
+// so a slow receiver throttles the workers as a slow manager would (see Do).
+// Like DoTasksErrors, if a task panics during execution,
+// the panic will be caught and sent as the Err of its TaskError
+// as a *PanicError,
+// and if an option halts the run early, such as WithMaxErrors,
+// a TaskError is sent for each input whose task did not finish
+// with the error that halted the run, such as ErrMaxErrors.
+func DoTasksErrChan[Input any](n int, items []Input, task func(Input) error, opts ...Option) <-chan TaskError[Input] {
+	ch := make(chan TaskError[Input])
+	go func() {
+		defer close(ch)
+		cfg := newConfig(opts)
+		cfg.managePanics = true
+		cfg.abandoned = func(in any, err error) {
+			ch <- TaskError[Input]{in.(Input), err}
+		}
+		_ = do(context.Background(), cfg, n, func(in Input) (void, error) {
+			return void{}, task(in)
+		}, func(in Input, _ void, err error) ([]Input, error) {
+			var pe *panicError
+			if errors.As(err, &pe) {
+				err = pe.err
+			}
+			if err != nil {
+				ch <- TaskError[Input]{in, err}
+			}
+			return nil, nil
+		}, items)
+	}()
+	return ch
+}
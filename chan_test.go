@@ -0,0 +1,265 @@
+package workgroup_test
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+	"golang.org/x/exp/slices"
+)
+
+func TestDoTasksChan(t *testing.T) {
+	var got []int
+	for r := range workgroup.DoTasksChan(3, []int{1, 2, 3, 4, 5},
+		func(n int) (int, error) {
+			if n == 4 {
+				return 0, fmt.Errorf("bad %d", n)
+			}
+			return n * n, nil
+		}) {
+		if r.Err != nil {
+			if r.In != 4 || r.Err.Error() != "bad 4" {
+				t.Fatal(r)
+			}
+			continue
+		}
+		if r.Out != r.In*r.In {
+			t.Fatal(r)
+		}
+		got = append(got, r.Out)
+	}
+	slices.Sort(got)
+	if fmt.Sprint(got) != "[1 4 9 25]" {
+		t.Fatal(got)
+	}
+}
+
+func TestDoTasksChanOrdered(t *testing.T) {
+	// Earlier inputs sleep longer, so they complete last.
+	var got []int
+	for r := range workgroup.DoTasksChanOrdered(5, []int{5, 4, 3, 2, 1},
+		func(n int) (int, error) {
+			time.Sleep(time.Duration(n) * 10 * time.Millisecond)
+			return n * 10, nil
+		}) {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		if r.Out != r.In*10 {
+			t.Fatal(r)
+		}
+		got = append(got, r.In)
+	}
+	if fmt.Sprint(got) != "[5 4 3 2 1]" {
+		t.Fatal(got)
+	}
+}
+
+func TestDoTasksChan_panic(t *testing.T) {
+	var last workgroup.Result[int, int]
+	for r := range workgroup.DoTasksChan(1, []int{1, 2, 3},
+		func(n int) (int, error) {
+			if n == 2 {
+				panic("boom")
+			}
+			return n, nil
+		}) {
+		last = r
+	}
+	if last.Err == nil || last.Err.Error() != "panic: boom" {
+		t.Fatal(last)
+	}
+}
+
+func TestDoTasksChanOrdered_halted(t *testing.T) {
+	var got []string
+	for r := range workgroup.DoTasksChanOrdered(1, []int{0, 1, 2, 3},
+		func(n int) (int, error) {
+			if n == 0 {
+				return 0, errors.New("bad")
+			}
+			return n, nil
+		}, workgroup.WithMaxErrors(1)) {
+		switch {
+		case r.Err == nil:
+			got = append(got, "ok")
+		case errors.Is(r.Err, workgroup.ErrMaxErrors):
+			got = append(got, "halted")
+		default:
+			got = append(got, r.Err.Error())
+		}
+		if len(got) == 1 && r.In != 0 {
+			t.Fatal(r)
+		}
+	}
+	// every item gets a Result, in order
+	if len(got) != 4 || got[0] != "bad" || got[3] != "halted" {
+		t.Fatal(got)
+	}
+}
+
+func TestDoTasksChanKeyed(t *testing.T) {
+	type request struct {
+		ID   int
+		Body string
+	}
+	reqs := []request{{1, "one"}, {2, "two"}, {3, "three"}}
+	byID := map[int]request{}
+	for _, req := range reqs {
+		byID[req.ID] = req
+	}
+	n := 0
+	for r := range workgroup.DoTasksChanKeyed(2, reqs,
+		func(req request) int { return req.ID },
+		func(req request) (int, error) {
+			return len(req.Body), nil
+		}) {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		if req := byID[r.Key]; len(req.Body) != r.Out {
+			t.Fatal(r)
+		}
+		n++
+	}
+	if n != 3 {
+		t.Fatal(n)
+	}
+}
+
+func TestDoTasksChanOrdered_window(t *testing.T) {
+	// The first task is slow, so every later result has to be held.
+	var started atomic.Int64
+	release := make(chan struct{})
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i
+	}
+	ch := workgroup.DoTasksChanOrdered(3, items, func(n int) (int, error) {
+		started.Add(1)
+		if n == 0 {
+			<-release
+		}
+		return n, nil
+	})
+	time.Sleep(20 * time.Millisecond)
+	if n := started.Load(); n > 3 {
+		t.Fatalf("dispatched %d tasks behind a stalled one", n)
+	}
+	close(release)
+	i := 0
+	for r := range ch {
+		if r.Err != nil || r.Out != i {
+			t.Fatal(r)
+		}
+		i++
+	}
+	if i != 50 {
+		t.Fatal(i)
+	}
+}
+
+func TestDoTasksChanOrdered_panic(t *testing.T) {
+	var got []int
+	var last workgroup.Result[int, int]
+	for r := range workgroup.DoTasksChanOrdered(3, []int{0, 1, 2, 3, 4},
+		func(n int) (int, error) {
+			if n == 1 {
+				// panic after the later results are held
+				time.Sleep(20 * time.Millisecond)
+				panic("boom")
+			}
+			return n, nil
+		}) {
+		if r.Err != nil {
+			last = r
+			continue
+		}
+		got = append(got, r.Out)
+	}
+	if last.In != 1 || last.Err.Error() != "panic: boom" {
+		t.Fatal(last)
+	}
+	if len(got) < 2 || got[0] != 0 || got[1] != 2 || !slices.IsSorted(got) {
+		t.Fatal(got)
+	}
+}
+
+func TestDoTasksChan_slowReceiver(t *testing.T) {
+	const workers = 4
+	var finished, received atomic.Int64
+	var maxWaiting int64
+	results := workgroup.DoTasksChan(workers, make([]int, 100), func(int) (int, error) {
+		finished.Add(1)
+		return 0, nil
+	})
+	for range results {
+		received.Add(1)
+		if waiting := finished.Load() - received.Load(); waiting > maxWaiting {
+			maxWaiting = waiting
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if received.Load() != 100 {
+		t.Fatal(received.Load())
+	}
+	// results buffered for the manager, plus one per blocked worker,
+	// plus the one the manager is trying to send
+	if maxWaiting > 2*workers+1 {
+		t.Fatal(maxWaiting)
+	}
+}
+
+func TestDoTasksErrChan(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	errOdd := errors.New("odd")
+	failed := map[int]error{}
+	for te := range workgroup.DoTasksErrChan(3, items, func(n int) error {
+		switch {
+		case n == 7:
+			panic("seven")
+		case n%2 == 1:
+			return errOdd
+		}
+		return nil
+	}) {
+		failed[te.In] = te.Err
+	}
+	if len(failed) != 5 {
+		t.Fatal(failed)
+	}
+	for _, n := range []int{1, 3, 5, 9} {
+		if failed[n] != errOdd {
+			t.Fatal(n, failed[n])
+		}
+	}
+	var pe *workgroup.PanicError
+	if !errors.As(failed[7], &pe) || pe.Value != "seven" {
+		t.Fatal(failed[7])
+	}
+	te := workgroup.TaskError[int]{3, errOdd}
+	if !errors.Is(te, errOdd) || te.Error() != "3: odd" {
+		t.Fatal(te)
+	}
+}
+
+func TestDoTasksErrChan_halted(t *testing.T) {
+	var halted []int
+	for te := range workgroup.DoTasksErrChan(1, []int{0, 1, 2, 3}, func(n int) error {
+		if n == 0 {
+			return errors.New("bad")
+		}
+		return nil
+	}, workgroup.WithMaxErrors(1)) {
+		if errors.Is(te.Err, workgroup.ErrMaxErrors) {
+			halted = append(halted, te.In)
+		}
+	}
+	// the inputs that never ran are reported
+	if len(halted) < 2 || halted[len(halted)-1] != 3 {
+		t.Fatal(halted)
+	}
+}
@@ -0,0 +1,92 @@
+package workgroup
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time for the time-based features of a run,
+// such as slow start, deferred inputs, and retry backoff.
+// It exists so that tests can substitute a fake clock
+// and step through delays without sleeping.
+// See WithClock.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	After(d time.Duration) <-chan time.Time
+}
+
+// Timer is a timer created by a Clock.
+// It behaves like *time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// WithClock has a run use c instead of the system clock.
+func WithClock(c Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = c
+	}
+}
+
+// withDeadline is context.WithDeadline for a deadline measured by clock.
+func withDeadline(ctx context.Context, clock Clock, deadline time.Time) (context.Context, context.CancelFunc) {
+	if _, ok := clock.(systemClock); ok {
+		return context.WithDeadline(ctx, deadline)
+	}
+	cctx, cancel := context.WithCancelCause(ctx)
+	t := clock.NewTimer(deadline.Sub(clock.Now()))
+	go func() {
+		select {
+		case <-t.C():
+			cancel(context.DeadlineExceeded)
+		case <-cctx.Done():
+			t.Stop()
+		}
+	}()
+	return clockDeadlineCtx{cctx, deadline}, func() { cancel(context.Canceled) }
+}
+
+// clockDeadlineCtx is a context canceled with cause context.DeadlineExceeded
+// when a Clock other than the system clock reaches its deadline.
+type clockDeadlineCtx struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c clockDeadlineCtx) Deadline() (time.Time, bool) { return c.deadline, true }
+
+func (c clockDeadlineCtx) Err() error {
+	err := c.Context.Err()
+	if err != nil && context.Cause(c.Context) == context.DeadlineExceeded {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// orSystemClock returns c, or the system clock if c is nil.
+func orSystemClock(c Clock) Clock {
+	if c == nil {
+		return systemClock{}
+	}
+	return c
+}
+
+// systemClock is the Clock used by default.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type systemTimer struct{ t *time.Timer }
+
+func (st systemTimer) C() <-chan time.Time { return st.t.C }
+
+func (st systemTimer) Stop() bool { return st.t.Stop() }
+
+func (st systemTimer) Reset(d time.Duration) bool { return st.t.Reset(d) }
@@ -0,0 +1,147 @@
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+// fakeClock is a Clock whose time only moves when told to.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	added  chan struct{} // receives each time a timer is set
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		added: make(chan struct{}, 100),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) workgroup.Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// advance moves time forward to when the earliest timer is due and fires it.
+// It returns how far time moved.
+func (c *fakeClock) advance() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var next *fakeTimer
+	for _, t := range c.timers {
+		if next == nil || t.when.Before(next.when) {
+			next = t
+		}
+	}
+	d := next.when.Sub(c.now)
+	c.now = next.when
+	c.removeLocked(next)
+	next.c <- c.now
+	return d
+}
+
+func (c *fakeClock) removeLocked(t *fakeTimer) bool {
+	for i := range c.timers {
+		if c.timers[i] == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	c     chan time.Time
+	when  time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.removeLocked(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.clock.removeLocked(t)
+	t.when = t.clock.now.Add(d)
+	t.clock.timers = append(t.clock.timers, t)
+	t.clock.added <- struct{}{}
+	return active
+}
+
+func TestWithClock_retryBudget(t *testing.T) {
+	clock := newFakeClock()
+	attempts := 0
+	task := workgroup.RetryBudget(clock, time.Hour, time.Minute,
+		func(context.Context, int) (int, error) {
+			attempts++
+			return 0, errors.New("unavailable")
+		})
+	done := make(chan error)
+	go func() {
+		_, err := task(context.Background(), 1)
+		done <- err
+	}()
+	var waited []time.Duration
+	for {
+		select {
+		case err := <-done:
+			// backoffs of 1, 2, 4, 8, and 16 minutes fit in the hour, but 32 more do not
+			if err == nil || attempts != 6 || len(waited) != 5 || waited[4] != 16*time.Minute {
+				t.Fatal(err, attempts, waited)
+			}
+			return
+		case <-clock.added:
+			waited = append(waited, clock.advance())
+		}
+	}
+}
+
+func TestWithClock_defer(t *testing.T) {
+	clock := newFakeClock()
+	tries := 0
+	done := make(chan error)
+	go func() {
+		done <- workgroup.DoTasks(1, []int{1}, func(int) error {
+			tries++
+			if tries < 3 {
+				return workgroup.Defer
+			}
+			return nil
+		}, workgroup.WithClock(clock), workgroup.WithDeferDelay(time.Hour))
+	}()
+	for i := 0; i < 2; i++ {
+		<-clock.added
+		clock.advance()
+	}
+	if err := <-done; err != nil || tries != 3 {
+		t.Fatal(err, tries)
+	}
+	if elapsed := clock.Now().Sub(newFakeClock().Now()); elapsed != 2*time.Hour {
+		t.Fatal(elapsed)
+	}
+}
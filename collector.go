@@ -0,0 +1,45 @@
+package workgroup
+
+import "sync"
+
+// GraphCollector is a concurrency safe map builder
+// for recording the results of a crawl,
+// such as which pages link to which.
+// It is meant to be filled in by a Do manager or tasks.
+type GraphCollector[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]V
+}
+
+// NewGraphCollector returns an empty GraphCollector.
+func NewGraphCollector[K comparable, V any]() *GraphCollector[K, V] {
+	return &GraphCollector[K, V]{m: make(map[K]V)}
+}
+
+// Add records v as the value for k, replacing any previous value.
+func (gc *GraphCollector[K, V]) Add(k K, v V) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.m[k] = v
+}
+
+// Map returns the collected map itself rather than a copy.
+// It must only be called once nothing else can call Add,
+// such as after Do has returned.
+// Use Snapshot to read the values while a crawl is still running.
+func (gc *GraphCollector[K, V]) Map() map[K]V {
+	return gc.m
+}
+
+// Snapshot returns a copy of the values collected so far.
+// Unlike Map, it is safe to call while a crawl is still running,
+// for example to report progress from a status handler.
+func (gc *GraphCollector[K, V]) Snapshot() map[K]V {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	m := make(map[K]V, len(gc.m))
+	for k, v := range gc.m {
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,55 @@
+package workgroup_test
+
+import (
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestGraphCollector_Snapshot(t *testing.T) {
+	gc := workgroup.NewGraphCollector[int, int]()
+	stop := make(chan struct{})
+	seen := make(chan int)
+	go func() {
+		most := 0
+		for {
+			select {
+			case <-stop:
+				seen <- most
+				return
+			default:
+			}
+			snap := gc.Snapshot()
+			for k, v := range snap {
+				if v != k*k {
+					t.Errorf("%d: %d", k, v)
+				}
+			}
+			if len(snap) < most {
+				t.Errorf("snapshot shrank: %d < %d", len(snap), most)
+			}
+			most = len(snap)
+			snap[-1] = -1 // must not affect the collector
+		}
+	}()
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+	err := workgroup.DoTasks(4, items, func(n int) error {
+		gc.Add(n, n*n)
+		return nil
+	})
+	close(stop)
+	<-seen
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := gc.Map()
+	if len(m) != 1000 {
+		t.Fatal(len(m))
+	}
+	if _, ok := m[-1]; ok {
+		t.Fatal("snapshot aliased collector")
+	}
+}
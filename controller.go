@@ -0,0 +1,83 @@
+package workgroup
+
+import "sync"
+
+// Controller adjusts a run while it is in progress.
+// Create one with NewController, pass it to DoWith with WithController,
+// and call its methods from the manager or from any other goroutine.
+// A Controller should only be used with one run at a time.
+type Controller struct {
+	mu      sync.Mutex
+	workers int
+	pending int
+	wake    chan void
+}
+
+// NewController returns a Controller that leaves a run unchanged
+// until one of its methods is called.
+func NewController() *Controller {
+	return &Controller{wake: make(chan void, 1)}
+}
+
+// WithController lets c adjust the run.
+func WithController(c *Controller) Option {
+	return func(cfg *config) {
+		cfg.controller = c
+	}
+}
+
+// SetWorkers changes how many tasks the run may have going at once,
+// for example to back off when a server starts rejecting requests.
+// n is clamped between 1 and the number of workers the run started with.
+// The pool itself keeps its size:
+// lowering the count leaves the extra workers idle
+// once their current tasks finish,
+// and raising it again puts them back to work.
+func (c *Controller) SetWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	c.mu.Lock()
+	c.workers = n
+	c.mu.Unlock()
+	c.poke()
+}
+
+// Pending returns how many inputs the run has queued
+// that have not yet been dispatched to a worker,
+// including inputs waiting to be retried after Defer,
+// so that a manager can hold back new work while the backlog is large.
+// The count is updated each time the run is about to dispatch or manage a task,
+// so while the manager runs,
+// it does not yet include the inputs the manager is about to return.
+func (c *Controller) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending
+}
+
+// setPending records the size of the run's backlog for Pending.
+func (c *Controller) setPending(n int) {
+	c.mu.Lock()
+	c.pending = n
+	c.mu.Unlock()
+}
+
+// limit returns the number of tasks the run may have going at once
+// if the run has max workers.
+func (c *Controller) limit(max int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.workers == 0 || c.workers > max {
+		return max
+	}
+	return c.workers
+}
+
+// poke wakes up the run to notice a change.
+func (c *Controller) poke() {
+	select {
+	case c.wake <- void{}:
+	default:
+	}
+}
@@ -0,0 +1,78 @@
+package workgroup_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestController_SetWorkers(t *testing.T) {
+	items := make([]int, 30)
+	for i := range items {
+		items[i] = i
+	}
+	var before, after gauge
+	ctl := workgroup.NewController()
+	backedOff := false
+	err := workgroup.DoWith(4, func(n int) (int, error) {
+		g := &before
+		if n >= 20 {
+			g = &after
+		}
+		g.enter()
+		defer g.exit()
+		time.Sleep(2 * time.Millisecond)
+		if n == 8 {
+			return 0, errors.New("429 Too Many Requests")
+		}
+		return n, nil
+	}, func(n, _ int, err error) ([]int, error) {
+		if err != nil && !backedOff {
+			backedOff = true
+			ctl.SetWorkers(1)
+		}
+		return nil, nil
+	}, items, workgroup.WithController(ctl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak := before.peak.Load(); peak < 2 {
+		t.Fatal(peak)
+	}
+	if peak := after.peak.Load(); peak != 1 {
+		t.Fatal(peak)
+	}
+}
+
+func TestController_Pending(t *testing.T) {
+	ctl := workgroup.NewController()
+	most, throttled := 0, false
+	err := workgroup.DoWith(2, func(n int) (int, error) {
+		return n, nil
+	}, func(n, _ int, err error) ([]int, error) {
+		if err != nil {
+			return nil, err
+		}
+		pending := ctl.Pending()
+		if pending > most {
+			most = pending
+		}
+		if n >= 1000 {
+			return nil, nil
+		}
+		// only discover more work while the backlog is small
+		if pending >= 3 {
+			throttled = true
+			return nil, nil
+		}
+		return []int{n*10 + 1, n*10 + 2, n*10 + 3, n*10 + 4, n*10 + 5}, nil
+	}, []int{1}, workgroup.WithController(ctl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if most > 7 || !throttled {
+		t.Fatal(most, throttled)
+	}
+}
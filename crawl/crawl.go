@@ -0,0 +1,80 @@
+// Package crawl packages the common boilerplate of a concurrent web crawl
+// on top of workgroup.
+package crawl
+
+import (
+	"context"
+	"errors"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+// Crawl visits every URL reachable from start using n concurrent workers
+// (or GOMAXPROCS workers if n < 1).
+// fetch is called once for each URL and returns the URLs it links to.
+// Each URL is fetched at most once, so cycles in the link graph are harmless.
+// Crawl returns the link graph of the URLs that were fetched successfully.
+// Errors returned by fetch do not halt the crawl,
+// but are joined into a multierror return value.
+// Once ctx is done, no more URLs are fetched,
+// and ctx.Err() is returned along with the partial graph.
+func Crawl(ctx context.Context, n int, start []string, fetch func(ctx context.Context, url string) ([]string, error)) (map[string][]string, error) {
+	c := Crawler{Workers: n, Fetch: fetch}
+	return c.Crawl(ctx, start...)
+}
+
+// Crawler is a reusable configuration for a crawl.
+// Only Fetch is required.
+type Crawler struct {
+	// Workers is the number of concurrent workers
+	// (or GOMAXPROCS workers if it is less than 1).
+	Workers int
+	// Fetch fetches a URL and returns the URLs it links to.
+	// It may return workgroup.Stop to end the crawl early without an error.
+	Fetch func(ctx context.Context, url string) ([]string, error)
+	// Retries is how many more times a URL is fetched after Fetch fails for it.
+	// Retries are fetched ahead of any other pending URLs.
+	Retries int
+	// Options are passed along to workgroup.DoContext.
+	Options []workgroup.Option
+}
+
+// Crawl visits every URL reachable from start as Crawl does,
+// retrying URLs that fail as configured by c.Retries.
+// Only the error of the last attempt for a URL is returned.
+func (c *Crawler) Crawl(ctx context.Context, start ...string) (map[string][]string, error) {
+	graph := make(map[string][]string)
+	tries := make(map[string]int)
+	var errs []error
+	opts := []workgroup.Option{
+		workgroup.WithDedup(func(url string) string { return url }),
+		workgroup.WithRetryPriority(),
+	}
+	opts = append(opts, c.Options...)
+	err := workgroup.DoContext(ctx, c.Workers, c.Fetch, func(url string, links []string, err error) ([]string, error) {
+		if errors.Is(err, workgroup.Stop) {
+			return nil, err
+		}
+		if err != nil {
+			if tries[url] < c.Retries {
+				tries[url]++
+				return []string{url}, nil
+			}
+			errs = append(errs, err)
+			return nil, nil
+		}
+		graph[url] = links
+		// a link back to url itself would be taken for a retry
+		next := make([]string, 0, len(links))
+		for _, link := range links {
+			if link != url {
+				next = append(next, link)
+			}
+		}
+		return next, nil
+	}, start, opts...)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return graph, errors.Join(errs...)
+}
@@ -0,0 +1,110 @@
+package crawl_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/carlmjohnson/workgroup"
+	"github.com/carlmjohnson/workgroup/crawl"
+)
+
+func TestCrawl(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.FS(fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte("/a.html")},
+		"a.html":     &fstest.MapFile{Data: []byte("/b1.html\n/b2.html\n/missing.html")},
+		"b1.html":    &fstest.MapFile{Data: []byte("/c.html")},
+		"b2.html":    &fstest.MapFile{Data: []byte("/c.html")},
+		"c.html":     &fstest.MapFile{Data: []byte("/")},
+	})))
+	defer srv.Close()
+	cl := srv.Client()
+
+	fetch := func(ctx context.Context, u string) ([]string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+u, nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := cl.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", u, res.Status)
+		}
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Split(string(body), "\n"), nil
+	}
+
+	graph, err := crawl.Crawl(context.Background(), 4, []string{"/"}, fetch)
+	if err == nil || err.Error() != "/missing.html: 404 Not Found" {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"/":        {"/a.html"},
+		"/a.html":  {"/b1.html", "/b2.html", "/missing.html"},
+		"/b1.html": {"/c.html"},
+		"/b2.html": {"/c.html"},
+		"/c.html":  {"/"},
+	}
+	if fmt.Sprint(graph) != fmt.Sprint(want) {
+		t.Fatal(graph)
+	}
+}
+
+func TestCrawler(t *testing.T) {
+	var mu sync.Mutex
+	tries := map[string]int{}
+	c := crawl.Crawler{
+		Workers: 2,
+		Retries: 2,
+		Fetch: func(ctx context.Context, u string) ([]string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			tries[u]++
+			switch u {
+			case "/":
+				return []string{"/", "/flaky", "/down", "/stop"}, nil
+			case "/flaky":
+				if tries[u] < 3 {
+					return nil, errors.New("flaky")
+				}
+				return []string{"/after"}, nil
+			case "/down":
+				return nil, errors.New("down")
+			}
+			return nil, nil
+		},
+	}
+	graph, err := c.Crawl(context.Background(), "/")
+	if err == nil || err.Error() != "down" {
+		t.Fatal(err)
+	}
+	if tries["/"] != 1 || tries["/flaky"] != 3 || tries["/down"] != 3 || len(graph) != 4 {
+		t.Fatal(tries, graph)
+	}
+
+	c.Retries = 0
+	c.Fetch = func(ctx context.Context, u string) ([]string, error) {
+		if u == "/stop" {
+			return nil, workgroup.Stop
+		}
+		return []string{"/stop", "/never"}, nil
+	}
+	graph, err = c.Crawl(context.Background(), "/")
+	// pages fetched alongside /stop may still be recorded
+	if _, ok := graph["/"]; err != nil || !ok || len(graph) > 2 {
+		t.Fatal(err, graph)
+	}
+}
@@ -0,0 +1,88 @@
+package crawl_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing/fstest"
+
+	"github.com/carlmjohnson/workgroup"
+	"github.com/carlmjohnson/workgroup/crawl"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+func ExampleCrawler() {
+	// Example site to crawl with recursive links
+	srv := httptest.NewServer(http.FileServer(http.FS(fstest.MapFS{
+		"index.html": &fstest.MapFile{
+			Data: []byte("/a.html"),
+		},
+		"a.html": &fstest.MapFile{
+			Data: []byte("/b1.html\n/b2.html"),
+		},
+		"b1.html": &fstest.MapFile{
+			Data: []byte("/c.html"),
+		},
+		"b2.html": &fstest.MapFile{
+			Data: []byte("/c.html"),
+		},
+		"c.html": &fstest.MapFile{
+			Data: []byte("/"),
+		},
+	})))
+	defer srv.Close()
+	cl := srv.Client()
+
+	// The crawler handles the visited set, retries, and the results graph;
+	// Fetch only has to fetch a page and extract the URLs
+	c := crawl.Crawler{
+		Workers: workgroup.MaxProcs,
+		Retries: 3,
+		Fetch: func(ctx context.Context, u string) ([]string, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+u, nil)
+			if err != nil {
+				return nil, err
+			}
+			res, err := cl.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				return nil, err
+			}
+			return strings.Split(string(body), "\n"), nil
+		},
+	}
+	graph, err := c.Crawl(context.Background(), "/")
+	if err != nil {
+		fmt.Println("error", err)
+	}
+
+	keys := maps.Keys(graph)
+	slices.Sort(keys)
+	for _, key := range keys {
+		fmt.Println(key, "links to:")
+		for _, v := range graph[key] {
+			fmt.Println("- ", v)
+		}
+	}
+
+	// Output:
+	// / links to:
+	// -  /a.html
+	// /a.html links to:
+	// -  /b1.html
+	// -  /b2.html
+	// /b1.html links to:
+	// -  /c.html
+	// /b2.html links to:
+	// -  /c.html
+	// /c.html links to:
+	// -  /
+}
@@ -1,15 +1,65 @@
 package workgroup
 
 import (
+	"context"
+	"errors"
 	"fmt"
-
-	"github.com/carlmjohnson/deque"
+	"reflect"
+	"time"
 )
 
 // Use GOMAXPROCS workers when doing tasks.
 const MaxProcs = -1
 
+// Use one worker per initial input when doing tasks.
+// Because the pool is sized when a run starts,
+// inputs returned by a manager share those workers.
+// To guard against accidentally starting millions of goroutines,
+// a run panics if Unlimited would start more workers
+// than the ceiling set by WithMaxGoroutines,
+// which defaults to DefaultMaxGoroutines.
+// Functions that do not have their inputs up front,
+// such as DoLines, NewGroup, and the stages of a Pipe,
+// panic if passed Unlimited.
+const Unlimited = -2
+
+// DefaultMaxGoroutines is the default ceiling
+// on the number of workers started for Unlimited.
+const DefaultMaxGoroutines = 100_000
+
+// Stop may be returned by a manager to end processing early without an error.
+var Stop = errors.New("workgroup: stop")
+
+// ErrDeadline is returned by a run that was cut short
+// because the deadline of its context passed.
+// The returned error also matches context.DeadlineExceeded.
+var ErrDeadline = errors.New("workgroup: deadline reached")
+
+// ErrStopped is returned by DoTasksSignal
+// when a signal stopped the run early.
+// The returned error also matches context.Canceled.
+var ErrStopped = errors.New("workgroup: stopped by signal")
+
+// ErrMaxErrors is returned by a run that halted
+// because its tasks failed as many times as WithMaxErrors allows.
+var ErrMaxErrors = errors.New("workgroup: too many errors")
+
+// ErrMaxTasks is returned by a run that halted
+// because it dispatched as many tasks as WithMaxTasks allows
+// and still had inputs left to work through.
+var ErrMaxTasks = errors.New("workgroup: too many tasks")
+
+// Defer may be returned by a task that cannot make progress yet,
+// for example because it was rate limited,
+// to have its input queued again after a delay (see WithDeferDelay)
+// instead of passing a failure to the manager.
+var Defer = errors.New("workgroup: defer")
+
 // Manager is a function that serially examines Task results to see if it produced any new Inputs.
+// Within a run, the manager is only ever called by one goroutine at a time,
+// and each call happens after the one before it has returned,
+// so it may freely read and write state in its closure,
+// such as a set of visited inputs, without locking.
 type Manager[Input, Output any] func(Input, Output, error) ([]Input, error)
 
 // Task is a function that can concurrently transform an input into an output.
@@ -19,38 +69,643 @@ type Task[Input, Output any] func(in Input) (out Output, err error)
 // which produce output consumed by a serially run manager.
 // The manager should return a slice of new task inputs based on prior task results,
 // or return an error to halt processing.
+// Completed results wait for the manager rather than piling up in memory:
+// a worker whose result has not been taken blocks,
+// so a slow manager throttles the pool,
+// and at most about twice as many results as there are workers
+// are held waiting for it at any time.
+// Once the manager has been called with a result,
+// Do keeps no reference to it, so the manager alone decides what is kept,
+// and a long crawl holds little more than its queue of pending inputs,
+// unless an option that has to remember inputs or outputs is used,
+// such as WithDedup, WithCycleDetection, or WithStopCondition.
+// If the manager returns Stop, processing halts and Do returns nil.
+// If a task returns Defer, its input is retried later
+// without the manager being called.
 // If a task panics during execution,
 // the panic will be caught and returned as an error halting further execution.
+// Do does not return until any tasks already running have finished.
 func Do[Input, Output any](n int, task Task[Input, Output], manager Manager[Input, Output], initial ...Input) error {
-	in, out := start(n, task)
+	return DoWith(n, task, manager, initial)
+}
+
+// DoWith is like Do, but it takes its initial inputs as a slice
+// so that it can accept options.
+func DoWith[Input, Output any](n int, task Task[Input, Output], manager Manager[Input, Output], initial []Input, opts ...Option) error {
+	return do(context.Background(), newConfig(opts), n, task, manager, initial)
+}
+
+// DoContext is like DoWith, but each task is passed a context derived from ctx.
+// If processing halts early,
+// that context is canceled with a cause (see context.Cause)
+// that tells any tasks still running why they are being torn down:
+//
+//   - Stop if the manager returned Stop
+//   - the manager's error if it returned some other error
+//   - the panic error if a task panicked
+//
+// Once every task has finished, the context is canceled
+// with context.Canceled as its cause if it was not already canceled.
+// If ctx itself is canceled, its cause is inherited instead.
+// A task that starts a nested run, such as with DoContext or DoTasksContext,
+// should pass along the context it was given
+// so that canceling the outer run also stops the nested one.
+//
+// If ctx is done, no new tasks are dispatched,
+// and once the running tasks have been managed,
+// DoContext returns ctx.Err(), marked with ErrDeadline if its deadline passed.
+// An error from the manager takes precedence over cancellation,
+// so errors.Is(err, context.Canceled) only reports true
+// when the run was cut short by ctx
+// or the manager chose to return context.Canceled.
+func DoContext[Input, Output any](ctx context.Context, n int, task func(context.Context, Input) (Output, error), manager Manager[Input, Output], initial []Input, opts ...Option) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	cfg := newConfig(opts)
+	cfg.onHalt = cancel
+	return doContext(ctx, cfg, n, task, manager, initial)
+}
+
+// do is DoWith, but once ctx is done it stops dispatching new tasks,
+// waits for the running tasks to be managed, and returns ctx.Err()
+// (see ctxErr).
+// If the run halts early on a panic or manager error,
+// do waits for the running tasks to return without managing them
+// (unless WithDeliverOnFailFast is set).
+func do[Input, Output any](ctx context.Context, cfg *config, n int, task Task[Input, Output], manager Manager[Input, Output], initial []Input) error {
+	return doContext(ctx, cfg, n, func(_ context.Context, in Input) (Output, error) {
+		return task(in)
+	}, manager, initial)
+}
+
+// doContext is do, but each task is passed a context derived from ctx
+// that carries the index of its worker (see WorkerID).
+func doContext[Input, Output any](ctx context.Context, cfg *config, n int, task func(context.Context, Input) (Output, error), manager Manager[Input, Output], initial []Input) (err error) {
+	checkInputTypes[Input](cfg)
+	var stopWhen func(out any) bool
+	if cfg.stopCondition != nil {
+		checkOutputType[Output]("WithStopCondition", cfg.stopType)
+		stopWhen = cfg.stopCondition()
+	}
+	workers := cfg.poolSize(ctx, n, len(initial))
+	if cfg.shuffle != nil {
+		initial = append([]Input(nil), initial...)
+		cfg.shuffle.Shuffle(len(initial), func(i, j int) {
+			initial[i], initial[j] = initial[j], initial[i]
+		})
+	}
+	var slow *slowTracker
+	if cfg.slowestN > 0 {
+		slow = &slowTracker{n: cfg.slowestN}
+		defer func() { *cfg.slowest = slow.sorted() }()
+	}
+	var live *liveness
+	if cfg.onStuck != nil {
+		live = newLiveness(workers)
+		go live.watch(cfg)
+		defer live.stop()
+	}
+	workerCtxs := make([]context.Context, workers)
+	for i := range workerCtxs {
+		workerCtxs[i] = context.WithValue(ctx, workerKey{}, i)
+	}
+	in, out := start(cfg, workers, func(worker int, it item[Input]) (out Output, err error) {
+		if cfg.plan != nil {
+			return out, nil
+		}
+		if cfg.limiter != nil {
+			if err = cfg.limiter.Acquire(ctx); err != nil {
+				return out, err
+			}
+			defer cfg.limiter.Release()
+		}
+		taskCtx := workerCtxs[worker]
+		if cfg.acquire != nil {
+			var release func()
+			if taskCtx, release, err = cfg.acquire(taskCtx); err != nil {
+				return out, err
+			}
+			defer release()
+		}
+		if slow != nil {
+			defer func(start time.Time) {
+				slow.add(it.in, cfg.clock.Now().Sub(start))
+			}(cfg.clock.Now())
+		}
+		if cfg.taskCanceler != nil {
+			var done func()
+			taskCtx, done = cfg.taskCanceler.track(taskCtx, it.in)
+			defer done()
+		}
+		if live != nil {
+			live.begin(worker, it.in, cfg.clock.Now())
+			defer live.end(worker)
+		}
+		if tr := cfg.trace; tr != nil {
+			tr.write(traceEvent{Time: cfg.clock.Now(), Event: "start", Input: formatInput(it.in), Worker: &worker})
+			defer func() {
+				tr.write(traceEvent{Time: cfg.clock.Now(), Event: "finish", Input: formatInput(it.in), Worker: &worker, Err: errString(err)})
+			}()
+		}
+		return task(taskCtx, it.in)
+	})
+	if cfg.cleanup != nil {
+		defer func() {
+			r := recover()
+			if r != nil {
+				err = panicErr(r)
+			}
+			// wait for the workers to exit
+			for range out {
+			}
+			cfg.cleanup(err)
+			if r != nil {
+				panic(r)
+			}
+		}()
+	}
 	defer close(in)
-	queue := deque.Of(initial...)
+	// limit caps inflight while the pool is ramping up
+	limit := workers
+	var ramp <-chan time.Time
+	var rampTimer Timer
+	if cfg.slowStart > 0 && workers > 1 {
+		limit = 1
+		rampTimer = cfg.clock.NewTimer(cfg.slowStart)
+		defer rampTimer.Stop()
+		ramp = rampTimer.C()
+	}
+	// relief caps inflight while WithMemoryPressure reports memory is short
+	relief := workers
+	var pressure <-chan time.Time
+	var pressureTimer Timer
+	if cfg.memoryPressure != nil && workers > 1 {
+		pressureTimer = cfg.clock.NewTimer(pressureInterval)
+		defer pressureTimer.Stop()
+		pressure = pressureTimer.C()
+	}
+	queue := newFrontier[Input](cfg, len(initial))
+	defer queue.close()
+	dropped := 0
+	if cfg.dropped != nil {
+		defer func() { *cfg.dropped = dropped }()
+	}
+	var seen map[any]void
+	if cfg.dedupKey != nil {
+		seen = make(map[any]void)
+	}
+	tr := cfg.trace
+	enqueue := func(ancestors *path, in Input) {
+		skip := func() {
+			if tr != nil {
+				tr.emit(cfg.clock.Now(), "skip", in, nil)
+			}
+		}
+		if cfg.isDone != nil && cfg.isDone(in) {
+			skip()
+			return
+		}
+		if seen != nil {
+			key := cfg.dedupKey(in)
+			if _, ok := seen[key]; ok {
+				skip()
+				return
+			}
+			seen[key] = void{}
+		}
+		it, ok := newItem(cfg, ancestors, in)
+		if !ok {
+			skip()
+			return
+		}
+		if cfg.maxPending > 0 && queue.Len() >= cfg.maxPending {
+			switch cfg.overflow {
+			case OverflowDropNewest:
+				dropped++
+				if tr != nil {
+					tr.emit(cfg.clock.Now(), "drop", in, nil)
+				}
+				return
+			case OverflowDropOldest:
+				dropped++
+				if old, ok := queue.Evict(); ok && tr != nil {
+					tr.emit(cfg.clock.Now(), "drop", old.in, nil)
+				}
+			}
+		}
+		queue.PushTail(it)
+		if tr != nil {
+			tr.emit(cfg.clock.Now(), "enqueue", in, nil)
+		}
+	}
+	// nth returns the input of ins to queue nth,
+	// reversing ins under FrontierLIFO so that its first input is on top
+	nth := func(ins []Input, n int) Input {
+		if cfg.policy.kind == lifo {
+			return ins[len(ins)-1-n]
+		}
+		return ins[n]
+	}
+	for i := range initial {
+		enqueue(nil, nth(initial, i))
+	}
+	// deferred holds inputs whose tasks returned Defer, in the order they are due
+	var deferred []deferral[Input]
+	var deferTimer Timer
+	var wake <-chan time.Time
 	inflight := 0
-	for inflight > 0 || queue.Len() > 0 {
+	dispatched := 0
+	var inflightWeight int64
+	failures := 0
+	var errs []error
+	done := ctx.Done()
+	stopped := false
+	var halt error
+loop:
+	for inflight > 0 || (!stopped && (queue.Len() > 0 || len(deferred) > 0)) {
+		if queue.err != nil {
+			halt = queue.err
+			break
+		}
+		capped := cfg.maxTasks > 0 && dispatched >= cfg.maxTasks
+		if capped && inflight == 0 {
+			halt = ErrMaxTasks
+			break
+		}
+		if !stopped && ctx.Err() != nil {
+			// recheck the loop condition, since nothing may be left to wait for
+			stopped, done = true, nil
+			if tr != nil {
+				tr.emit(cfg.clock.Now(), "stop", nil, ctx.Err())
+			}
+			continue
+		}
 		inch := in
-		item, ok := queue.Head()
-		if !ok {
+		it, ok := queue.Head()
+		capacity := limit
+		if relief < capacity {
+			capacity = relief
+		}
+		var poked <-chan void
+		if cfg.controller != nil {
+			cfg.controller.setPending(queue.Len() + len(deferred))
+			if c := cfg.controller.limit(workers); c < capacity {
+				capacity = c
+			}
+			poked = cfg.controller.wake
+		}
+		if !ok || stopped || capped || (capacity < workers && inflight >= capacity) {
+			inch = nil
+		}
+		if cfg.backlog != nil && inflight+cfg.backlog() >= workers {
+			inch = nil
+		}
+		if inch != nil && cfg.policy.kind != fifo && len(out) > 0 {
+			// manage finished results first,
+			// since the inputs they return may go ahead of the queue
 			inch = nil
 		}
+		if inch != nil && cfg.weight != nil {
+			// the head waits for room rather than being passed over,
+			// so heavy inputs are not starved by light ones
+			it.weight = cfg.weight(it.in)
+			if inflight > 0 && inflightWeight+it.weight > cfg.maxWeight {
+				inch = nil
+			}
+		}
+		outch := out
+		if inch != nil && inflight < workers && cfg.maxPending > 0 &&
+			cfg.overflow == OverflowBlock && queue.Len() >= cfg.maxPending {
+			// drain the full queue before managing more results
+			// while some worker is idle and can take an input
+			outch = nil
+		}
+		if wake == nil && len(deferred) > 0 && !stopped {
+			d := deferred[0].due.Sub(cfg.clock.Now())
+			if deferTimer == nil {
+				deferTimer = cfg.clock.NewTimer(d)
+				defer deferTimer.Stop()
+			} else {
+				deferTimer.Reset(d)
+			}
+			wake = deferTimer.C()
+		}
 		select {
-		case inch <- item:
+		case <-done:
+			stopped, done = true, nil
+			if tr != nil {
+				tr.emit(cfg.clock.Now(), "stop", nil, ctx.Err())
+			}
+		case <-poked:
+		case <-ramp:
+			limit *= 2
+			if limit >= workers {
+				limit, ramp = workers, nil
+			} else {
+				rampTimer.Reset(cfg.slowStart)
+			}
+		case <-pressure:
+			if cfg.memoryPressure() {
+				if relief /= 2; relief < cfg.minWorkers {
+					relief = cfg.minWorkers
+				}
+			} else if relief *= 2; relief > workers {
+				relief = workers
+			}
+			pressureTimer.Reset(pressureInterval)
+		case now := <-wake:
+			wake = nil
+			for len(deferred) > 0 && !deferred[0].due.After(now) {
+				queue.PushTail(deferred[0].it)
+				if tr != nil {
+					tr.emit(now, "requeue", deferred[0].it.in, nil)
+				}
+				deferred = deferred[1:]
+			}
+		case inch <- it:
 			inflight++
+			dispatched++
+			inflightWeight += it.weight
 			queue.PopHead()
-		case r := <-out:
+			if tr != nil {
+				tr.emit(cfg.clock.Now(), "dispatch", it.in, nil)
+			}
+			if cfg.plan != nil {
+				cfg.plan(it.in)
+			}
+			if cfg.onQueueWait != nil {
+				if wait := cfg.clock.Now().Sub(it.queued); wait >= cfg.queueWaitThreshold {
+					cfg.onQueueWait(wait)
+				}
+			}
+		case r := <-outch:
 			inflight--
+			inflightWeight -= r.In.weight
 			if r.Panic != nil {
-				return panicErr(r.Panic)
+				switch {
+				case cfg.managePanics:
+					r.Err = &panicError{&PanicError{r.Panic, r.Stack}}
+				case cfg.collectPanics:
+					r.Err = &PanicError{r.Panic, r.Stack}
+				default:
+					halt = panicErr(r.Panic)
+					break loop
+				}
+			}
+			if errors.Is(r.Err, Defer) {
+				due := cfg.clock.Now().Add(cfg.deferDelay)
+				deferred = append(deferred, deferral[Input]{due, r.In})
+				if tr != nil {
+					tr.emit(cfg.clock.Now(), "defer", r.In.in, nil)
+				}
+				continue
+			}
+			if r.Err == nil && cfg.completed != nil && cfg.plan == nil {
+				cfg.completed(r.In.in)
+			}
+			items, err := manager(r.In.in, r.Out, r.Err)
+			if tr != nil {
+				tr.write(traceEvent{Time: cfg.clock.Now(), Event: "manage", Input: formatInput(r.In.in), Err: errString(r.Err), Returned: len(items)})
 			}
-			items, err := manager(r.In, r.Out, r.Err)
 			if err != nil {
-				return err
+				if !cfg.skipManagerErrors || errors.Is(err, Stop) {
+					halt = err
+					break loop
+				}
+				errs = append(errs, err)
+			}
+			if r.Err != nil && cfg.maxErrors > 0 {
+				if failures++; failures >= cfg.maxErrors {
+					halt = ErrMaxErrors
+					break loop
+				}
+			}
+			if r.Err == nil && stopWhen != nil && stopWhen(r.Out) {
+				halt = Stop
+				break loop
+			}
+			for i := range items {
+				in := nth(items, i)
+				if cfg.retryPriority && reflect.DeepEqual(in, r.In.in) {
+					queue.PushHead(r.In)
+					if tr != nil {
+						tr.emit(cfg.clock.Now(), "requeue", in, nil)
+					}
+					continue
+				}
+				enqueue(r.In.path, in)
 			}
-			queue.Append(items...)
 		}
 	}
-	return nil
+	if halt != nil {
+		if tr != nil {
+			tr.emit(cfg.clock.Now(), "halt", nil, halt)
+		}
+		if cfg.onHalt != nil {
+			cfg.onHalt(halt)
+		}
+		for ; inflight > 0; inflight-- {
+			r := <-out
+			if !cfg.deliverOnHalt || r.Panic != nil || r.Err != nil {
+				if cfg.abandoned != nil {
+					cfg.abandoned(r.In.in, halt)
+				}
+				continue
+			}
+			if cfg.completed != nil && cfg.plan == nil {
+				cfg.completed(r.In.in)
+			}
+			_, _ = manager(r.In.in, r.Out, nil)
+		}
+		if cfg.abandoned != nil {
+			for _, d := range deferred {
+				cfg.abandoned(d.it.in, halt)
+			}
+			for queue.Len() > 0 && queue.err == nil {
+				it, ok := queue.PopHead()
+				if !ok {
+					break
+				}
+				cfg.abandoned(it.in, halt)
+			}
+		}
+		if errors.Is(halt, Stop) {
+			return cfg.join(errs)
+		}
+		return halt
+	}
+	if stopped {
+		errs = append(errs, ctxErr(ctx))
+	}
+	return cfg.join(errs)
+}
+
+// ctxErr returns ctx.Err(), marked with ErrDeadline if the deadline of ctx passed.
+func ctxErr(ctx context.Context) error {
+	err := ctx.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrDeadline, err)
+	}
+	return err
+}
+
+// checkInputType panics if an option for inputs of type want
+// was passed to a run with inputs of another type.
+func checkInputType[Input any](option string, want reflect.Type) {
+	if want == nil {
+		return
+	}
+	if t := reflect.TypeOf((*Input)(nil)).Elem(); t != want {
+		panic(fmt.Sprintf("workgroup: %s for %v used with %v inputs", option, want, t))
+	}
 }
 
+// checkInputTypes panics if any option for inputs in cfg
+// is for inputs of another type than Input.
+func checkInputTypes[Input any](cfg *config) {
+	checkInputType[Input]("WithCycleDetection", cfg.cycleType)
+	checkInputType[Input]("WithDedup", cfg.dedupType)
+	checkInputType[Input]("WithCheckpoint", cfg.checkpointType)
+	checkInputType[Input]("WithDryRun", cfg.planType)
+	checkInputType[Input]("WithHeartbeat", cfg.heartbeatType)
+	checkInputType[Input]("FrontierPriority", cfg.policy.inType)
+	if cfg.taskCanceler != nil {
+		checkInputType[Input]("WithTaskCanceler", cfg.taskCanceler.inputType())
+	}
+}
+
+// viewInputs adapts the options for inputs in cfg
+// to a run whose inputs only stand for the caller's,
+// such as indexes into items,
+// so that the options are checked against Input
+// and see item(in) instead of each input in of the run.
+func viewInputs[Run, Input any](cfg *config, item func(Run) Input) {
+	checkInputTypes[Input](cfg)
+	view := func(in any) any { return item(in.(Run)) }
+	if f := cfg.cycleKey; f != nil {
+		cfg.cycleKey = func(in any) any { return f(view(in)) }
+	}
+	if f := cfg.dedupKey; f != nil {
+		cfg.dedupKey = func(in any) any { return f(view(in)) }
+	}
+	if f := cfg.completed; f != nil {
+		cfg.completed = func(in any) { f(view(in)) }
+	}
+	if f := cfg.isDone; f != nil {
+		cfg.isDone = func(in any) bool { return f(view(in)) }
+	}
+	if f := cfg.plan; f != nil {
+		cfg.plan = func(in any) { f(view(in)) }
+	}
+	if f := cfg.onStuck; f != nil {
+		cfg.onStuck = func(in any, running time.Duration) { f(view(in), running) }
+	}
+	if less := cfg.policy.less; less != nil {
+		cfg.policy.less = func(a, b any) bool { return less(view(a), view(b)) }
+	}
+	if cfg.taskCanceler != nil {
+		cfg.taskCanceler = viewTracker{cfg.taskCanceler, view}
+	}
+	cfg.cycleType, cfg.dedupType, cfg.checkpointType = nil, nil, nil
+	cfg.planType, cfg.heartbeatType, cfg.policy.inType = nil, nil, nil
+}
+
+// viewTracker is a taskTracker that tracks tasks by view(in).
+type viewTracker struct {
+	taskTracker
+	view func(in any) any
+}
+
+func (vt viewTracker) track(ctx context.Context, in any) (context.Context, func()) {
+	return vt.taskTracker.track(ctx, vt.view(in))
+}
+
+func (vt viewTracker) inputType() reflect.Type { return nil }
+
+// checkOutputType is checkInputType for options that apply to outputs.
+func checkOutputType[Output any](option string, want reflect.Type) {
+	if want == nil {
+		return
+	}
+	if t := reflect.TypeOf((*Output)(nil)).Elem(); t != want {
+		panic(fmt.Sprintf("workgroup: %s for %v used with %v outputs", option, want, t))
+	}
+}
+
+// item is an input queued by do along with its bookkeeping.
+type item[Input any] struct {
+	in     Input
+	path   *path
+	queued time.Time // set by the frontier only if WithQueueWait is used
+	weight int64     // set at dispatch only by DoMixed
+}
+
+// deferral is an item waiting to be queued again after its task returned Defer.
+type deferral[Input any] struct {
+	due time.Time
+	it  item[Input]
+}
+
+// path is a linked list of the keys of an item and its ancestors.
+type path struct {
+	key    any
+	parent *path
+}
+
+// newItem wraps an input returned by the manager
+// for an item with the given ancestors (nil for an initial input),
+// or reports false if the input should be skipped.
+func newItem[Input any](cfg *config, ancestors *path, in Input) (item[Input], bool) {
+	it := item[Input]{in: in}
+	if cfg.cycleKey == nil {
+		return it, true
+	}
+	key := cfg.cycleKey(in)
+	for p := ancestors; p != nil; p = p.parent {
+		if p.key != key {
+			continue
+		}
+		if cfg.onCycle != nil {
+			var cycle []any
+			for q := ancestors; q != p.parent; q = q.parent {
+				cycle = append(cycle, q.key)
+			}
+			for i, j := 0, len(cycle)-1; i < j; i, j = i+1, j-1 {
+				cycle[i], cycle[j] = cycle[j], cycle[i]
+			}
+			cfg.onCycle(append(cycle, key))
+		}
+		return it, false
+	}
+	it.path = &path{key, ancestors}
+	return it, true
+}
+
+// PanicError is a panic recovered from a task.
+// See WithCollectPanics.
+type PanicError struct {
+	Value any    // the value passed to panic
+	Stack []byte // the stack trace of the panicking task
+}
+
+func (pe *PanicError) Error() string { return panicErr(pe.Value).Error() }
+
+// Unwrap returns Value if it is an error.
+func (pe *PanicError) Unwrap() error {
+	err, _ := pe.Value.(error)
+	return err
+}
+
+// panicError marks a recovered panic passed to a manager
+// so that it can be told apart from an error returned by a task.
+type panicError struct {
+	err error
+}
+
+func (pe *panicError) Error() string { return pe.err.Error() }
+
+func (pe *panicError) Unwrap() error { return pe.err }
+
 func panicErr(v any) error {
 	if e, ok := v.(error); ok {
 		return e
@@ -2,10 +2,12 @@ package workgroup_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"runtime/pprof"
 	"strings"
 	"testing/fstest"
 	"time"
@@ -52,9 +54,10 @@ func ExampleDo() {
 		return strings.Split(string(body), "\n"), nil
 	}
 
-	// Manager keeps track of which pages have been visited and the results graph
+	// Manager keeps track of which pages have been visited
+	// and records the results graph in a collector
 	tried := map[string]int{}
-	results := map[string][]string{}
+	results := workgroup.NewGraphCollector[string, []string]()
 	manager := func(req string, urls []string, err error) ([]string, error) {
 		if err != nil {
 			// If there's a problem fetching a page, try three times
@@ -64,7 +67,7 @@ func ExampleDo() {
 			}
 			return nil, err
 		}
-		results[req] = urls
+		results.Add(req, urls)
 		var newurls []string
 		for _, u := range urls {
 			if tried[u] == 0 {
@@ -81,11 +84,12 @@ func ExampleDo() {
 		fmt.Println("error", err)
 	}
 
-	keys := maps.Keys(results)
+	graph := results.Map()
+	keys := maps.Keys(graph)
 	slices.Sort(keys)
 	for _, key := range keys {
 		fmt.Println(key, "links to:")
-		for _, v := range results[key] {
+		for _, v := range graph[key] {
 			fmt.Println("- ", v)
 		}
 	}
@@ -153,6 +157,30 @@ func ExampleDoFuncs() {
 	// executed concurrently? true
 }
 
+func ExampleDoFuncs_fill() {
+	var profile struct {
+		Name      string
+		Followers int
+		Joined    time.Time
+	}
+	err := workgroup.DoFuncs(3, func() error {
+		profile.Name = "Gopher"
+		return nil
+	}, func() error {
+		profile.Followers = 1009
+		return nil
+	}, func() error {
+		profile.Joined = time.Date(2009, time.November, 10, 0, 0, 0, 0, time.UTC)
+		return nil
+	})
+	if err != nil {
+		fmt.Println("error", err)
+	}
+	fmt.Println(profile.Name, profile.Followers, profile.Joined.Year())
+	// Output:
+	// Gopher 1009 2009
+}
+
 func ExampleDoTasks_cancel() {
 	// To cancel execution early, communicate via a context.CancelFunc
 	ctx, cancel := context.WithCancel(context.Background())
@@ -192,3 +220,61 @@ func ExampleDoTasks_cancel() {
 	// canceled
 	// exited promptly? true
 }
+
+func ExampleDrainResults() {
+	words := []string{"apple", "", "cherry"}
+	results := workgroup.DoTasksChan(1, words, func(word string) (int, error) {
+		if word == "" {
+			return 0, errors.New("empty word")
+		}
+		return len(word), nil
+	})
+	err := workgroup.DrainResults(results, func(r workgroup.Result[string, int]) {
+		if r.Err == nil {
+			fmt.Println(r.In, r.Out)
+		}
+	})
+	fmt.Println("error:", err)
+	// Output:
+	// apple 5
+	// cherry 6
+	// error: empty word
+}
+
+func ExampleScalingReport() {
+	// a synthetic workload where each task waits on a slow backend
+	task := func(int) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+	report := workgroup.ScalingReport(task, make([]int, 16), []int{1, 4, 16})
+	for i, r := range report {
+		faster := i > 0 && r.Throughput > report[i-1].Throughput
+		fmt.Printf("%d workers: error %v, faster than before? %v\n", r.Workers, r.Err, faster)
+	}
+	// Output:
+	// 1 workers: error <nil>, faster than before? false
+	// 4 workers: error <nil>, faster than before? true
+	// 16 workers: error <nil>, faster than before? true
+}
+
+func ExampleWithSpawn() {
+	// Label each worker goroutine so that profiles show which batch it belongs to
+	labels := pprof.Labels("batch", "nightly-report")
+	spawned := 0
+	spawn := func(f func()) {
+		spawned++
+		go pprof.Do(context.Background(), labels, func(context.Context) {
+			f()
+		})
+	}
+	err := workgroup.DoTasks(3, []int{1, 2, 3, 4, 5}, func(n int) error {
+		return nil
+	}, workgroup.WithSpawn(spawn))
+	if err != nil {
+		fmt.Println("error", err)
+	}
+	fmt.Println("spawned", spawned, "workers")
+	// Output:
+	// spawned 3 workers
+}
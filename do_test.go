@@ -1,11 +1,20 @@
 package workgroup_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/carlmjohnson/workgroup"
+	"golang.org/x/exp/slices"
 )
 
 func TestDo_panic(t *testing.T) {
@@ -77,3 +86,617 @@ func TestDoFuncs_panic(t *testing.T) {
 		t.Fatal(n.Load())
 	}
 }
+
+func TestDoTasksErrors(t *testing.T) {
+	errs := workgroup.DoTasksErrors(3, []int{1, 2, 3, 4, 5, 6},
+		func(n int) error {
+			if n%3 == 0 {
+				return fmt.Errorf("bad %d", n)
+			}
+			return nil
+		})
+	if fmt.Sprint(errs) != "[<nil> <nil> bad 3 <nil> <nil> bad 6]" {
+		t.Fatal(errs)
+	}
+}
+
+func TestDoTasksIndexed(t *testing.T) {
+	outs, errs := workgroup.DoTasksIndexed(3, []string{"1", "x", "3", "", "5"},
+		func(s string) (int, error) {
+			if s == "" {
+				panic("empty")
+			}
+			return strconv.Atoi(s)
+		})
+	if fmt.Sprint(outs) != "map[0:1 2:3 4:5]" {
+		t.Fatal(outs)
+	}
+	if len(errs) != 2 || errs[3].Error() != "panic: empty" || !errors.Is(errs[1], strconv.ErrSyntax) {
+		t.Fatal(errs)
+	}
+}
+
+func TestDoTasksErrors_panic(t *testing.T) {
+	errs := workgroup.DoTasksErrors(1, []int{1, 2, 3, 4},
+		func(n int) error {
+			switch n {
+			case 2:
+				panic("boom")
+			case 3:
+				return errors.New("bad")
+			}
+			return nil
+		})
+	if fmt.Sprint(errs) != "[<nil> panic: boom bad <nil>]" {
+		t.Fatal(errs)
+	}
+}
+
+func TestDoTasksErrors_halted(t *testing.T) {
+	var mu sync.Mutex
+	ran := map[int]bool{}
+	errs := workgroup.DoTasksErrors(1, []int{0, 1, 2, 3},
+		func(n int) error {
+			mu.Lock()
+			ran[n] = true
+			mu.Unlock()
+			if n == 0 {
+				return errors.New("bad")
+			}
+			return nil
+		}, workgroup.WithMaxErrors(1))
+	if errs[0] == nil || errs[0].Error() != "bad" {
+		t.Fatal(errs)
+	}
+	// inputs that did not finish are not reported as successes
+	for i, err := range errs[1:] {
+		if err == nil && !ran[i+1] || err != nil && !errors.Is(err, workgroup.ErrMaxErrors) {
+			t.Fatal(errs)
+		}
+	}
+	outs, ierrs := workgroup.DoTasksIndexed(1, []int{0, 1, 2, 3},
+		func(n int) (int, error) {
+			if n == 0 {
+				return 0, errors.New("bad")
+			}
+			return n, nil
+		}, workgroup.WithMaxErrors(1))
+	if len(outs)+len(ierrs) != 4 {
+		t.Fatal(outs, ierrs)
+	}
+	for i, err := range ierrs {
+		if i != 0 && !errors.Is(err, workgroup.ErrMaxErrors) {
+			t.Fatal(ierrs)
+		}
+	}
+}
+
+func TestDoTasksErrors_attribution(t *testing.T) {
+	items := make([]int, 60)
+	for i := range items {
+		items[i] = i
+	}
+	errs := workgroup.DoTasksErrors(8, items, func(n int) error {
+		time.Sleep(time.Duration(n%3) * time.Millisecond)
+		switch n % 3 {
+		case 1:
+			panic(n)
+		case 2:
+			return fmt.Errorf("error %d", n)
+		}
+		return nil
+	})
+	for i, err := range errs {
+		var pe *workgroup.PanicError
+		switch i % 3 {
+		case 0:
+			if err != nil {
+				t.Fatal(i, err)
+			}
+		case 1:
+			if !errors.As(err, &pe) || pe.Value != i {
+				t.Fatal(i, err)
+			}
+		case 2:
+			if errors.As(err, &pe) || err.Error() != fmt.Sprint("error ", i) {
+				t.Fatal(i, err)
+			}
+		}
+	}
+}
+
+func TestDoTasksCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var ran []int
+	err := workgroup.DoTasksCtx(ctx, 1, []int{1, 2, 3, 4, 5},
+		func(n int) error {
+			ran = append(ran, n)
+			if n == 2 {
+				cancel()
+				// let the dispatcher see the cancellation
+				time.Sleep(10 * time.Millisecond)
+			}
+			return nil
+		})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ran) != "[1 2]" {
+		t.Fatal(ran)
+	}
+}
+
+func TestDoTasksContext_alreadyCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := workgroup.DoTasksContext(ctx, 1, []int{1, 2, 3},
+		func(context.Context, int) error {
+			t.Error("task ran")
+			return nil
+		})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+}
+
+func TestDoTasksContext_deadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := workgroup.DoTasksContext(ctx, 1, []int{1, 2, 3},
+		func(ctx context.Context, _ int) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	if !errors.Is(err, workgroup.ErrDeadline) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	// a task's own timeout is a task failure, not ErrDeadline
+	err = workgroup.DoTasks(1, []int{1}, func(int) error {
+		return context.DeadlineExceeded
+	})
+	if errors.Is(err, workgroup.ErrDeadline) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+}
+
+func TestDoTasksIndexedContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	outs, err := workgroup.DoTasksIndexedContext(ctx, 1, []string{"a", "b", "c", "d", "e"},
+		func(_ context.Context, s string) (string, error) {
+			if s == "c" {
+				cancel()
+				// let the dispatcher see the cancellation
+				time.Sleep(10 * time.Millisecond)
+			}
+			return strings.ToUpper(s), nil
+		})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(outs) != "map[0:A 1:B 2:C]" {
+		t.Fatal(outs)
+	}
+}
+
+func TestDoTasksContext_cancelOrFail(t *testing.T) {
+	errBad := errors.New("bad")
+	for _, tc := range []struct {
+		name     string
+		fail     bool
+		cancel   bool
+		canceled bool
+	}{
+		{"canceled", false, true, true},
+		{"failed", true, false, false},
+		{"both", true, true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var started sync.WaitGroup
+			started.Add(3)
+			err := workgroup.DoTasksContext(ctx, 3, []int{1, 2, 3},
+				func(ctx context.Context, n int) error {
+					started.Done()
+					started.Wait()
+					if n == 1 && tc.cancel {
+						cancel()
+					}
+					if n == 2 && tc.fail {
+						return errBad
+					}
+					if tc.cancel {
+						<-ctx.Done()
+						return ctx.Err()
+					}
+					return nil
+				})
+			if got := errors.Is(err, context.Canceled); got != tc.canceled {
+				t.Fatal(err)
+			}
+			if got := errors.Is(err, errBad); got != tc.fail {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestDoWith_managerErrorSkip(t *testing.T) {
+	// A tree where every node n has children 2n and 2n+1, up to 15
+	task := func(n int) ([]int, error) {
+		if 2*n > 15 {
+			return nil, nil
+		}
+		return []int{2 * n, 2*n + 1}, nil
+	}
+	var seen []int
+	manager := func(n int, children []int, err error) ([]int, error) {
+		seen = append(seen, n)
+		if n == 2 {
+			return nil, fmt.Errorf("bad node %d", n)
+		}
+		return children, nil
+	}
+	err := workgroup.DoWith(3, task, manager, []int{1},
+		workgroup.WithManagerErrorSkip())
+	if err == nil || err.Error() != "bad node 2" {
+		t.Fatal(err)
+	}
+	slices.Sort(seen)
+	// 2's branch (4, 5, 8-11) is dropped
+	if fmt.Sprint(seen) != "[1 2 3 6 7 12 13 14 15]" {
+		t.Fatal(seen)
+	}
+}
+
+func TestDoWith_cycleDetection(t *testing.T) {
+	site := map[string][]string{
+		"/":        {"/a.html"},
+		"/a.html":  {"/b1.html", "/b2.html"},
+		"/b1.html": {"/c.html"},
+		"/b2.html": {"/c.html"},
+		"/c.html":  {"/"},
+	}
+	task := func(u string) ([]string, error) {
+		return site[u], nil
+	}
+	visits := map[string]int{}
+	manager := func(u string, links []string, err error) ([]string, error) {
+		visits[u]++
+		// no deduplication: follow every link
+		return links, nil
+	}
+	var cycles []string
+	err := workgroup.DoWith(2, task, manager, []string{"/"},
+		workgroup.WithCycleDetection(
+			func(u string) string { return u },
+			func(cycle []string) {
+				cycles = append(cycles, strings.Join(cycle, " > "))
+			}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(visits) != "map[/:1 /a.html:1 /b1.html:1 /b2.html:1 /c.html:2]" {
+		t.Fatal(visits)
+	}
+	slices.Sort(cycles)
+	if fmt.Sprint(cycles) != "[/ > /a.html > /b1.html > /c.html > / / > /a.html > /b2.html > /c.html > /]" {
+		t.Fatal(cycles)
+	}
+}
+
+func TestWithCycleDetection_mismatch(t *testing.T) {
+	defer func() {
+		if r := recover(); fmt.Sprint(r) != "workgroup: WithCycleDetection for int used with string inputs" {
+			t.Fatal(r)
+		}
+	}()
+	_ = workgroup.DoWith(1, func(s string) (int, error) { return 0, nil },
+		func(string, int, error) ([]string, error) { return nil, nil },
+		[]string{"a"},
+		workgroup.WithCycleDetection(func(n int) int { return n }, nil))
+	t.Fatal("no panic")
+}
+
+func TestDoContext_cause(t *testing.T) {
+	errBad := errors.New("bad")
+	for _, want := range []error{workgroup.Stop, errBad} {
+		var started sync.WaitGroup
+		started.Add(2)
+		var cause error
+		task := func(ctx context.Context, n int) (int, error) {
+			// make sure both tasks are running before either is managed
+			started.Done()
+			started.Wait()
+			if n == 2 {
+				<-ctx.Done()
+				cause = context.Cause(ctx)
+			}
+			return n, nil
+		}
+		manager := func(n, _ int, _ error) ([]int, error) {
+			return nil, want
+		}
+		err := workgroup.DoContext(context.Background(), 2, task, manager, []int{1, 2})
+		if want == workgroup.Stop && err != nil {
+			t.Fatal(err)
+		}
+		if want == errBad && err != errBad {
+			t.Fatal(err)
+		}
+		if cause != want {
+			t.Fatal(cause, want)
+		}
+	}
+}
+
+func TestDo_serialManager(t *testing.T) {
+	var g gauge
+	visited := map[int]bool{}
+	err := workgroup.Do(8, func(n int) (int, error) {
+		return n, nil
+	}, func(n, _ int, err error) ([]int, error) {
+		g.enter()
+		defer g.exit()
+		// unsynchronized state: the race detector flags concurrent calls
+		visited[n] = true
+		if n >= 500 {
+			return nil, nil
+		}
+		return []int{2*n + 1, 2*n + 2}, nil
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak := g.peak.Load(); peak != 1 {
+		t.Fatal(peak)
+	}
+	if len(visited) != 1001 {
+		t.Fatal(len(visited))
+	}
+}
+
+func TestDo_retention(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large crawl in short mode")
+	}
+	const (
+		pages    = 4000
+		pageSize = 64 << 10
+	)
+	heap := func() uint64 {
+		runtime.GC()
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return m.HeapAlloc
+	}
+	base := heap()
+	var peak uint64
+	managed := 0
+	// the pages add up to 250MiB, but each is dropped once it is managed
+	err := workgroup.Do(4, func(n int) ([]byte, error) {
+		return make([]byte, pageSize), nil
+	}, func(n int, page []byte, err error) ([]int, error) {
+		if managed++; managed%500 == 0 {
+			if h := heap(); h > peak {
+				peak = h
+			}
+		}
+		var links []int
+		for _, link := range []int{2*n + 1, 2*n + 2} {
+			if link < pages {
+				links = append(links, link)
+			}
+		}
+		return links, err
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if managed != pages {
+		t.Fatal(managed)
+	}
+	if peak > base && peak-base > 16<<20 {
+		t.Fatalf("heap grew by %d bytes", peak-base)
+	}
+}
+
+func TestDoFuncsResults(t *testing.T) {
+	results, err := workgroup.DoFuncsResults(3,
+		func() (string, error) {
+			time.Sleep(10 * time.Millisecond)
+			return "users", nil
+		},
+		func() (string, error) { return "", errors.New("orders unavailable") },
+		func() (string, error) { return "products", nil },
+	)
+	if err == nil || err.Error() != "orders unavailable" {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%q", results) != `["users" "" "products"]` {
+		t.Fatal(results)
+	}
+}
+
+func TestDoFuncs_fill(t *testing.T) {
+	// run with -race: each function writes a distinct field
+	var report struct {
+		users  []string
+		orders int
+		cached bool
+	}
+	err := workgroup.DoFuncs(3, func() error {
+		time.Sleep(10 * time.Millisecond)
+		report.users = []string{"ann", "bob"}
+		return nil
+	}, func() error {
+		report.orders = 42
+		return nil
+	}, func() error {
+		report.cached = true
+		panic("cache down")
+	})
+	if err == nil || err.Error() != "panic: cache down" {
+		t.Fatal(err)
+	}
+	// the slow function finished before DoFuncs returned
+	if len(report.users) != 2 || report.orders != 42 || !report.cached {
+		t.Fatal(report)
+	}
+}
+
+func TestDoNamedFuncs(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	err := workgroup.DoNamedFuncs(2,
+		workgroup.NamedFunc{Name: "migrate-db", Func: func() error {
+			time.Sleep(10 * time.Millisecond)
+			return errRefused
+		}},
+		workgroup.NamedFunc{Name: "warm-cache", Func: func() error { return nil }},
+		workgroup.NamedFunc{Name: "load-config", Func: func() error { panic("no config") }},
+	)
+	if !errors.Is(err, errRefused) {
+		t.Fatal(err)
+	}
+	var pe *workgroup.PanicError
+	if !errors.As(err, &pe) {
+		t.Fatal(err)
+	}
+	// errors are in argument order, not completion order
+	if !strings.HasPrefix(err.Error(), "step 'migrate-db': connection refused\nstep 'load-config': ") {
+		t.Fatal(err)
+	}
+	if err := workgroup.DoNamedFuncs(2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFan(t *testing.T) {
+	outs, err := workgroup.Fan(3, "Hello, World",
+		func(s string) (string, error) { return strings.ToUpper(s), nil },
+		func(s string) (string, error) { return strings.ToLower(s), nil },
+		func(s string) (string, error) { return "", errors.New("no translation for " + s) },
+		func(s string) (string, error) { return strings.Fields(s)[0], nil },
+	)
+	if err == nil || err.Error() != "no translation for Hello, World" {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%q", outs) != `["HELLO, WORLD" "hello, world" "" "Hello,"]` {
+		t.Fatal(outs)
+	}
+}
+
+func TestDoTasksRemaining(t *testing.T) {
+	var mu sync.Mutex
+	var seen []int
+	deferred := false
+	err := workgroup.DoTasksRemaining(3, make([]int, 20), func(remaining, _ int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if remaining == 10 && !deferred {
+			deferred = true
+			return workgroup.Defer
+		}
+		seen = append(seen, remaining)
+		return nil
+	}, workgroup.WithDeferDelay(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(seen)
+	// the deferred input starts again, so one count may repeat
+	if len(seen) != 20 || seen[0] != 0 || seen[1] != 1 || seen[19] != 19 {
+		t.Fatal(seen)
+	}
+}
+
+func TestDefer(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	var managed []string
+	start := time.Now()
+	err := workgroup.DoWith(2, func(u string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[u]++
+		if u == "/limited" && attempts[u] == 1 {
+			// pretend the server said 429 Too Many Requests
+			return "", workgroup.Defer
+		}
+		return "ok", nil
+	}, func(u, body string, err error) ([]string, error) {
+		managed = append(managed, u+" "+body)
+		return nil, err
+	}, []string{"/limited", "/a", "/b"},
+		workgroup.WithDeferDelay(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts["/limited"] != 2 {
+		t.Fatal(attempts)
+	}
+	if len(managed) != 3 || managed[2] != "/limited ok" {
+		t.Fatal(managed)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+}
+
+func TestDoContext_nested(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	nestedErr := make(chan error, 1)
+	start := time.Now()
+	err := workgroup.DoContext(ctx, 2, func(ctx context.Context, section string) ([]string, error) {
+		// each section fetches its pages with a nested run
+		err := workgroup.DoTasksContext(ctx, 2, []int{1, 2, 3, 4},
+			func(ctx context.Context, page int) error {
+				if section == "/a" && page == 1 {
+					cancel()
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Second):
+					return nil
+				}
+			})
+		if section == "/a" {
+			nestedErr <- err
+		}
+		return nil, err
+	}, func(string, []string, error) ([]string, error) {
+		return nil, nil
+	}, []string{"/a", "/b"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	if err := <-nestedErr; !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+}
+
+// gauge tracks how many tasks are running at once.
+type gauge struct {
+	running, peak atomic.Int64
+}
+
+func (g *gauge) enter() {
+	n := g.running.Add(1)
+	for {
+		old := g.peak.Load()
+		if n <= old || g.peak.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+func (g *gauge) exit() {
+	g.running.Add(-1)
+}
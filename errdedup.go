@@ -0,0 +1,59 @@
+package workgroup
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WithErrorDedup collapses repeated errors in the multierror returned by a run,
+// so that a batch in which the same downstream failure hit many tasks
+// reports it once, as in "service unavailable (occurred 42 times)",
+// instead of as a wall of identical lines.
+// Errors are considered the same if their Error methods return the same string.
+// The first occurrence of each error is kept for errors.Is and errors.As,
+// and errors are listed in the order of their first occurrence.
+func WithErrorDedup() Option {
+	return func(cfg *config) {
+		cfg.errorDedup = true
+	}
+}
+
+// join joins errs into a multierror,
+// collapsing repeated errors if WithErrorDedup is set.
+func (cfg *config) join(errs []error) error {
+	if !cfg.errorDedup {
+		return errors.Join(errs...)
+	}
+	var unique []error
+	counts := make(map[string]int)
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		msg := err.Error()
+		if counts[msg] == 0 {
+			unique = append(unique, err)
+		}
+		counts[msg]++
+	}
+	for i, err := range unique {
+		if n := counts[err.Error()]; n > 1 {
+			unique[i] = &repeatedError{err, n}
+		}
+	}
+	return errors.Join(unique...)
+}
+
+// repeatedError is an error that occurred more than once in a run.
+type repeatedError struct {
+	err error
+	n   int
+}
+
+func (re *repeatedError) Error() string {
+	return fmt.Sprintf("%v (occurred %d times)", re.err, re.n)
+}
+
+func (re *repeatedError) Unwrap() error {
+	return re.err
+}
@@ -0,0 +1,356 @@
+package workgroup
+
+import (
+	"container/heap"
+	"encoding/gob"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/carlmjohnson/deque"
+)
+
+// WithFrontierSpill bounds the memory used by the queue of pending inputs.
+// Once more than threshold inputs are waiting to be dispatched,
+// further inputs are written in segments of threshold inputs
+// to temporary files in dir
+// (or the default directory for temporary files if dir is empty)
+// and read back one segment at a time as the queue drains.
+// Dispatch order is unchanged.
+//
+// Spilled inputs are encoded with encoding/gob,
+// so Input must be a type that gob can encode and decode,
+// such as a string or a struct with exported fields.
+// An error writing or reading a segment halts the run.
+// Bookkeeping for WithCycleDetection is kept in memory.
+// WithFrontierSpill panics if threshold is less than 1.
+func WithFrontierSpill(dir string, threshold int) Option {
+	if threshold < 1 {
+		panic("workgroup: WithFrontierSpill called with threshold < 1")
+	}
+	return func(cfg *config) {
+		cfg.spillDir = dir
+		cfg.spillThreshold = threshold
+	}
+}
+
+// FrontierPolicy decides which queued input a run dispatches next.
+// See WithFrontierPolicy.
+type FrontierPolicy struct {
+	kind   policyKind
+	less   func(a, b any) bool
+	inType reflect.Type
+}
+
+type policyKind int
+
+const (
+	fifo policyKind = iota
+	lifo
+	priority
+)
+
+var (
+	// FrontierFIFO dispatches inputs in the order they were queued,
+	// so a crawl goes breadth first.
+	// It is the default.
+	FrontierFIFO = FrontierPolicy{kind: fifo}
+	// FrontierLIFO dispatches the input queued most recently,
+	// taking the inputs returned by one manager call in the order returned,
+	// so a crawl goes depth first
+	// and keeps a smaller frontier than it would breadth first.
+	FrontierLIFO = FrontierPolicy{kind: lifo}
+)
+
+// FrontierPriority returns a FrontierPolicy
+// that dispatches the queued input that sorts first according to less,
+// taking inputs that tie in the order they were queued.
+// The Input type must match the Input type of the run;
+// if it does not, the run panics before starting any tasks.
+func FrontierPriority[Input any](less func(a, b Input) bool) FrontierPolicy {
+	return FrontierPolicy{
+		kind:   priority,
+		less:   func(a, b any) bool { return less(a.(Input), b.(Input)) },
+		inType: reflect.TypeOf((*Input)(nil)).Elem(),
+	}
+}
+
+// WithFrontierPolicy sets which queued input a run dispatches next
+// whenever a worker is free.
+// Retries queued under WithRetryPriority are still dispatched
+// before any other input.
+// With a policy other than FrontierFIFO,
+// finished results are managed before the next input is dispatched
+// where possible, so that the inputs they lead to are in the running,
+// and WithFrontierSpill has no effect.
+func WithFrontierPolicy(policy FrontierPolicy) Option {
+	return func(cfg *config) {
+		cfg.policy = policy
+	}
+}
+
+// frontier is the queue of items waiting to be dispatched.
+// Under FrontierFIFO, items are taken from mem first,
+// then from segments, then from tail,
+// so mem is only empty when the whole frontier is.
+// Under FrontierLIFO, items are taken from the tail of mem,
+// and under FrontierPriority, they are kept in pq instead.
+type frontier[Input any] struct {
+	kind      policyKind
+	pq        *priorityQueue[Input]
+	mem       *deque.Deque[item[Input]]
+	dir       string
+	threshold int
+	segments  []segment
+	spilled   int
+	tail      []item[Input]
+	err       error
+	// now stamps items as they are queued, if WithQueueWait is used
+	now func() time.Time
+}
+
+// segment is a file of spilled inputs
+// along with the paths and queue times of the items they came from.
+type segment struct {
+	name   string
+	paths  []*path
+	queued []time.Time
+}
+
+func newFrontier[Input any](cfg *config, size int) *frontier[Input] {
+	f := &frontier[Input]{
+		kind:      cfg.policy.kind,
+		mem:       deque.Make[item[Input]](size),
+		dir:       cfg.spillDir,
+		threshold: cfg.spillThreshold,
+	}
+	switch f.kind {
+	case lifo:
+		f.threshold = 0
+	case priority:
+		f.threshold = 0
+		f.pq = &priorityQueue[Input]{less: cfg.policy.less}
+	}
+	if cfg.onQueueWait != nil {
+		f.now = cfg.clock.Now
+	}
+	return f
+}
+
+func (f *frontier[Input]) Len() int {
+	if f.pq != nil {
+		return len(f.pq.entries)
+	}
+	return f.mem.Len() + f.spilled + len(f.tail)
+}
+
+func (f *frontier[Input]) Head() (item[Input], bool) {
+	switch f.kind {
+	case lifo:
+		return f.mem.Tail()
+	case priority:
+		if len(f.pq.entries) == 0 {
+			return item[Input]{}, false
+		}
+		return f.pq.entries[0].it, true
+	}
+	return f.mem.Head()
+}
+
+func (f *frontier[Input]) PushTail(it item[Input]) {
+	if f.now != nil {
+		it.queued = f.now()
+	}
+	if f.pq != nil {
+		f.pq.push(it, false)
+		return
+	}
+	if f.threshold == 0 ||
+		(len(f.segments) == 0 && len(f.tail) == 0 && f.mem.Len() < f.threshold) {
+		f.mem.PushTail(it)
+		return
+	}
+	f.tail = append(f.tail, it)
+	if len(f.tail) >= f.threshold && f.err == nil {
+		f.err = f.spill()
+	}
+}
+
+func (f *frontier[Input]) PushHead(it item[Input]) {
+	if f.now != nil {
+		it.queued = f.now()
+	}
+	switch f.kind {
+	case lifo:
+		f.mem.PushTail(it)
+	case priority:
+		f.pq.push(it, true)
+	default:
+		f.mem.PushHead(it)
+	}
+}
+
+func (f *frontier[Input]) PopHead() (item[Input], bool) {
+	switch f.kind {
+	case lifo:
+		return f.mem.PopTail()
+	case priority:
+		if len(f.pq.entries) == 0 {
+			return item[Input]{}, false
+		}
+		return heap.Pop(f.pq).(pqEntry[Input]).it, true
+	}
+	it, ok := f.mem.PopHead()
+	if f.mem.Len() > 0 || f.err != nil {
+		return it, ok
+	}
+	if len(f.segments) > 0 {
+		f.err = f.unspill()
+	} else {
+		for _, it := range f.tail {
+			f.mem.PushTail(it)
+		}
+		f.tail = f.tail[:0]
+	}
+	return it, ok
+}
+
+// Evict removes and returns the item that has waited the longest,
+// or under FrontierPriority, the item that would be dispatched last.
+func (f *frontier[Input]) Evict() (item[Input], bool) {
+	switch f.kind {
+	case lifo:
+		return f.mem.PopHead()
+	case priority:
+		if len(f.pq.entries) == 0 {
+			return item[Input]{}, false
+		}
+		last := 0
+		for i := range f.pq.entries {
+			if f.pq.Less(last, i) {
+				last = i
+			}
+		}
+		return heap.Remove(f.pq, last).(pqEntry[Input]).it, true
+	}
+	return f.PopHead()
+}
+
+// spill writes tail to a new segment.
+func (f *frontier[Input]) spill() error {
+	file, err := os.CreateTemp(f.dir, "workgroup-frontier-*")
+	if err != nil {
+		return err
+	}
+	seg := segment{name: file.Name()}
+	ins := make([]Input, len(f.tail))
+	for i, it := range f.tail {
+		ins[i] = it.in
+		if it.path != nil {
+			if seg.paths == nil {
+				seg.paths = make([]*path, len(f.tail))
+			}
+			seg.paths[i] = it.path
+		}
+		if f.now != nil {
+			if seg.queued == nil {
+				seg.queued = make([]time.Time, len(f.tail))
+			}
+			seg.queued[i] = it.queued
+		}
+	}
+	err = gob.NewEncoder(file).Encode(ins)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(seg.name)
+		return err
+	}
+	f.segments = append(f.segments, seg)
+	f.spilled += len(ins)
+	f.tail = f.tail[:0]
+	return nil
+}
+
+// unspill reads the oldest segment back into mem.
+func (f *frontier[Input]) unspill() error {
+	seg := f.segments[0]
+	file, err := os.Open(seg.name)
+	if err != nil {
+		return err
+	}
+	var ins []Input
+	err = gob.NewDecoder(file).Decode(&ins)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	os.Remove(seg.name)
+	f.segments = f.segments[1:]
+	f.spilled -= len(ins)
+	for i, in := range ins {
+		it := item[Input]{in: in}
+		if seg.paths != nil {
+			it.path = seg.paths[i]
+		}
+		if seg.queued != nil {
+			it.queued = seg.queued[i]
+		}
+		f.mem.PushTail(it)
+	}
+	return nil
+}
+
+// close removes any segments that were never read back.
+func (f *frontier[Input]) close() {
+	for _, seg := range f.segments {
+		os.Remove(seg.name)
+	}
+}
+
+// priorityQueue is the heap of items for FrontierPriority.
+type priorityQueue[Input any] struct {
+	entries []pqEntry[Input]
+	less    func(a, b any) bool
+	seq     int
+}
+
+type pqEntry[Input any] struct {
+	it     item[Input]
+	seq    int
+	urgent bool // queued by PushHead
+}
+
+func (pq *priorityQueue[Input]) push(it item[Input], urgent bool) {
+	pq.seq++
+	heap.Push(pq, pqEntry[Input]{it, pq.seq, urgent})
+}
+
+func (pq *priorityQueue[Input]) Len() int { return len(pq.entries) }
+
+func (pq *priorityQueue[Input]) Less(i, j int) bool {
+	a, b := pq.entries[i], pq.entries[j]
+	switch {
+	case a.urgent != b.urgent:
+		return a.urgent
+	case pq.less(a.it.in, b.it.in):
+		return true
+	case pq.less(b.it.in, a.it.in):
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func (pq *priorityQueue[Input]) Swap(i, j int) {
+	pq.entries[i], pq.entries[j] = pq.entries[j], pq.entries[i]
+}
+
+func (pq *priorityQueue[Input]) Push(x any) { pq.entries = append(pq.entries, x.(pqEntry[Input])) }
+
+func (pq *priorityQueue[Input]) Pop() any {
+	old := pq.entries
+	x := old[len(old)-1]
+	pq.entries = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,92 @@
+package workgroup_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestWithFrontierSpill(t *testing.T) {
+	dir := t.TempDir()
+	var order []int
+	maxFiles := 0
+	task := func(n int) (int, error) { return n, nil }
+	manager := func(n, _ int, err error) ([]int, error) {
+		order = append(order, n)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > maxFiles {
+			maxFiles = len(entries)
+		}
+		if n >= 50 {
+			return nil, nil
+		}
+		return []int{2*n + 1, 2*n + 2}, nil
+	}
+	err := workgroup.DoWith(1, task, manager, []int{0},
+		workgroup.WithFrontierSpill(dir, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 101 {
+		t.Fatal(len(order))
+	}
+	// a single worker visits the tree breadth first
+	for i, n := range order {
+		if i != n {
+			t.Fatal(order)
+		}
+	}
+	if maxFiles == 0 {
+		t.Fatal("frontier never spilled")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatal(entries)
+	}
+}
+
+func TestWithFrontierPolicy(t *testing.T) {
+	tree := map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/a/1", "/a/2"},
+		"/b": {"/b/1", "/b/2"},
+	}
+	crawl := func(opts ...workgroup.Option) string {
+		var order []string
+		err := workgroup.DoWith(1, func(page string) ([]string, error) {
+			order = append(order, page)
+			return tree[page], nil
+		}, func(_ string, links []string, _ error) ([]string, error) {
+			return links, nil
+		}, []string{"/"}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(order, " ")
+	}
+	for _, tc := range []struct {
+		name   string
+		policy workgroup.FrontierPolicy
+		want   string
+	}{
+		{"fifo", workgroup.FrontierFIFO, "/ /a /b /a/1 /a/2 /b/1 /b/2"},
+		{"lifo", workgroup.FrontierLIFO, "/ /a /a/1 /a/2 /b /b/1 /b/2"},
+		{"priority", workgroup.FrontierPriority(func(a, b string) bool { return a > b }),
+			"/ /b /b/2 /b/1 /a /a/2 /a/1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if got := crawl(workgroup.WithFrontierPolicy(tc.policy)); got != tc.want {
+					t.Fatal(got)
+				}
+			}
+		})
+	}
+	if got := crawl(); got != "/ /a /b /a/1 /a/2 /b/1 /b/2" {
+		t.Fatal(got)
+	}
+}
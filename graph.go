@@ -0,0 +1,120 @@
+package workgroup
+
+import "errors"
+
+// ErrCycle is returned by DoGraph if the dependencies contain a cycle.
+var ErrCycle = errors.New("workgroup: dependency cycle")
+
+// DoGraph starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and calls run for each node of a dependency graph,
+// starting a node only once every node it depends on has run successfully.
+// deps maps each node to the nodes it depends on;
+// nodes that only appear as dependencies are run as well.
+// Independent nodes run concurrently.
+//
+// If the dependencies contain a cycle,
+// DoGraph returns ErrCycle without running anything.
+// If run fails for a node, the nodes that depend on it,
+// directly or indirectly, are skipped,
+// while the rest of the graph keeps going,
+// and the errors are joined into a multierror return value.
+// If run panics during execution,
+// the panic will be caught and returned as an error halting further execution.
+func DoGraph[Node comparable](n int, deps map[Node][]Node, run func(Node) error, opts ...Option) error {
+	waiting, dependents, ready := buildGraph(deps)
+	if countNodes(graphLevels(waiting, dependents, ready)) < len(waiting) {
+		return ErrCycle
+	}
+	var errs []error
+	err := DoWith(n, func(node Node) (void, error) {
+		return void{}, run(node)
+	}, func(node Node, _ void, err error) ([]Node, error) {
+		if err != nil {
+			errs = append(errs, err)
+			return nil, nil
+		}
+		var next []Node
+		for _, d := range dependents[node] {
+			if waiting[d]--; waiting[d] == 0 {
+				next = append(next, d)
+			}
+		}
+		return next, nil
+	}, ready, opts...)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return newConfig(opts).join(errs)
+}
+
+// GraphLevels returns the nodes of a dependency graph,
+// given as for DoGraph, grouped into levels:
+// the first level holds the nodes with no dependencies,
+// and each later level holds the nodes whose dependencies
+// are all in earlier levels.
+// The nodes of a level can run concurrently,
+// so the levels preview how DoGraph would work through the graph
+// (see also WithDryRun).
+// The nodes within a level are in no particular order.
+// If the dependencies contain a cycle,
+// GraphLevels returns ErrCycle.
+func GraphLevels[Node comparable](deps map[Node][]Node) ([][]Node, error) {
+	waiting, dependents, ready := buildGraph(deps)
+	levels := graphLevels(waiting, dependents, ready)
+	if countNodes(levels) < len(waiting) {
+		return nil, ErrCycle
+	}
+	return levels, nil
+}
+
+// buildGraph returns how many dependencies each node is waiting for,
+// the nodes that depend on each node,
+// and the nodes that are ready to run.
+func buildGraph[Node comparable](deps map[Node][]Node) (waiting map[Node]int, dependents map[Node][]Node, ready []Node) {
+	waiting = make(map[Node]int)
+	dependents = make(map[Node][]Node)
+	for node, ds := range deps {
+		waiting[node] += len(ds)
+		for _, d := range ds {
+			waiting[d] += 0
+			dependents[d] = append(dependents[d], node)
+		}
+	}
+	for node, count := range waiting {
+		if count == 0 {
+			ready = append(ready, node)
+		}
+	}
+	return waiting, dependents, ready
+}
+
+// graphLevels groups the nodes that can become ready into levels.
+// Nodes that are part of or downstream from a cycle are left out.
+func graphLevels[Node comparable](waiting map[Node]int, dependents map[Node][]Node, ready []Node) [][]Node {
+	counts := make(map[Node]int, len(waiting))
+	for node, count := range waiting {
+		counts[node] = count
+	}
+	var levels [][]Node
+	for level := ready; len(level) > 0; {
+		levels = append(levels, level)
+		var next []Node
+		for _, node := range level {
+			for _, d := range dependents[node] {
+				if counts[d]--; counts[d] == 0 {
+					next = append(next, d)
+				}
+			}
+		}
+		level = next
+	}
+	return levels
+}
+
+func countNodes[Node any](levels [][]Node) int {
+	n := 0
+	for _, level := range levels {
+		n += len(level)
+	}
+	return n
+}
@@ -0,0 +1,143 @@
+package workgroup_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+	"golang.org/x/exp/slices"
+)
+
+func TestDoGraph(t *testing.T) {
+	// a diamond: app depends on lib1 and lib2, which both depend on base
+	deps := map[string][]string{
+		"app":  {"lib1", "lib2"},
+		"lib1": {"base"},
+		"lib2": {"base"},
+	}
+	var (
+		mu       sync.Mutex
+		finished = map[string]time.Time{}
+		started  = map[string]time.Time{}
+		g        gauge
+	)
+	err := workgroup.DoGraph(4, deps, func(node string) error {
+		g.enter()
+		defer g.exit()
+		mu.Lock()
+		started[node] = time.Now()
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		finished[node] = time.Now()
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(finished) != 4 {
+		t.Fatal(finished)
+	}
+	for node, ds := range deps {
+		for _, d := range ds {
+			if started[node].Before(finished[d]) {
+				t.Fatalf("%s started before %s finished", node, d)
+			}
+		}
+	}
+	// lib1 and lib2 run side by side
+	if peak := g.peak.Load(); peak != 2 {
+		t.Fatal(peak)
+	}
+}
+
+func TestDoGraph_failure(t *testing.T) {
+	errBad := errors.New("bad")
+	deps := map[string][]string{
+		"app":   {"lib1", "lib2"},
+		"lib1":  {"base"},
+		"lib2":  {"base"},
+		"tests": {"lib2"},
+	}
+	var mu sync.Mutex
+	ran := map[string]bool{}
+	err := workgroup.DoGraph(4, deps, func(node string) error {
+		mu.Lock()
+		ran[node] = true
+		mu.Unlock()
+		if node == "lib1" {
+			return errBad
+		}
+		return nil
+	})
+	if !errors.Is(err, errBad) {
+		t.Fatal(err)
+	}
+	if ran["app"] || !ran["tests"] || len(ran) != 4 {
+		t.Fatal(ran)
+	}
+}
+
+func TestDoGraph_cycle(t *testing.T) {
+	deps := map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+		"d": {},
+	}
+	ran := false
+	err := workgroup.DoGraph(2, deps, func(string) error {
+		ran = true
+		return nil
+	})
+	if err != workgroup.ErrCycle || ran {
+		t.Fatal(err, ran)
+	}
+}
+
+func TestGraphLevels(t *testing.T) {
+	levels, err := workgroup.GraphLevels(map[string][]string{
+		"app":   {"lib1", "lib2"},
+		"lib1":  {"base"},
+		"lib2":  {"base"},
+		"tests": {"app", "lib1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, level := range levels {
+		slices.Sort(level)
+	}
+	if fmt.Sprint(levels) != "[[base] [lib1 lib2] [app] [tests]]" {
+		t.Fatal(levels)
+	}
+	_, err = workgroup.GraphLevels(map[string][]string{"a": {"b"}, "b": {"a"}})
+	if !errors.Is(err, workgroup.ErrCycle) {
+		t.Fatal(err)
+	}
+}
+
+func TestDoGraph_dryRun(t *testing.T) {
+	var planned []string
+	err := workgroup.DoGraph(4, map[string][]string{
+		"app":  {"lib"},
+		"lib":  {"base"},
+		"docs": nil,
+	}, func(node string) error {
+		t.Errorf("ran %s", node)
+		return nil
+	}, workgroup.WithDryRun(func(node string) {
+		planned = append(planned, node)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(planned) != 4 || slices.Index(planned, "base") > slices.Index(planned, "lib") ||
+		slices.Index(planned, "lib") > slices.Index(planned, "app") {
+		t.Fatal(planned)
+	}
+}
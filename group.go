@@ -0,0 +1,228 @@
+package workgroup
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Group is a reusable pool of workers that run submitted tasks.
+//
+// A Group starts out open. While it is open,
+// Submit hands a task to the next free worker.
+// Calling Close moves the Group to closed:
+// no more tasks may be submitted,
+// and the workers exit once the tasks already submitted have finished.
+// There is no way to reopen a closed Group.
+// The workers of a Group that is never closed by Close or Wait
+// wait for tasks forever and are leaked.
+//
+// Submit and Close are safe to call from multiple goroutines.
+type Group struct {
+	mu      sync.Mutex
+	closed  bool
+	closing chan void
+	senders sync.WaitGroup
+	in      chan<- func(context.Context) error
+	done    chan void
+	err     error
+	workers int
+	clock   Clock
+	prewarm *prewarmer
+	// cancel cancels the context passed to tasks submitted with SubmitContext
+	// once Shutdown's grace period is over
+	cancel context.CancelFunc
+}
+
+// NewGroup starts a Group with n workers (or GOMAXPROCS workers if n < 1).
+// Options that affect how tasks are run, such as WithPanicHandler,
+// WithWorkerSetup, and WithErrorDedup, apply to the Group.
+// NewGroup panics if n is Unlimited, since the tasks are not known up front.
+func NewGroup(n int, opts ...Option) *Group {
+	checkUnlimited("NewGroup", n)
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	cfg := newConfig(opts)
+	cfg.prewarm = &prewarmer{start: make(chan void)}
+	cfg.prewarm.wg.Add(n)
+	ctx, cancel := context.WithCancel(context.Background())
+	workerCtxs := make([]context.Context, n)
+	for i := range workerCtxs {
+		workerCtxs[i] = context.WithValue(ctx, workerKey{}, i)
+	}
+	in, out := start(cfg, n, func(worker int, task func(context.Context) error) (void, error) {
+		return void{}, task(workerCtxs[worker])
+	})
+	g := &Group{
+		closing: make(chan void),
+		in:      in,
+		done:    make(chan void),
+		workers: n,
+		clock:   cfg.clock,
+		prewarm: cfg.prewarm,
+		cancel:  cancel,
+	}
+	go func() {
+		var errs []error
+		for r := range out {
+			if r.Panic != nil {
+				errs = append(errs, panicErr(r.Panic))
+				continue
+			}
+			if r.Err != nil {
+				errs = append(errs, r.Err)
+			}
+		}
+		g.err = cfg.join(errs)
+		g.cancel()
+		close(g.done)
+	}()
+	return g
+}
+
+// Submit blocks until a worker is free and then hands it task.
+// Submit panics if the Group is closed
+// before task could be handed to a worker.
+func (g *Group) Submit(task func() error) {
+	g.SubmitContext(func(context.Context) error {
+		return task()
+	})
+}
+
+// SubmitContext is like Submit,
+// but task is passed a context that carries the index of its worker
+// (see WorkerID) and that is canceled
+// if Shutdown gives up waiting for the Group to finish.
+func (g *Group) SubmitContext(task func(ctx context.Context) error) {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		panic("workgroup: Submit called on closed Group")
+	}
+	g.senders.Add(1)
+	g.mu.Unlock()
+	defer g.senders.Done()
+	select {
+	case g.in <- task:
+	case <-g.closing:
+		panic("workgroup: Submit called on closed Group")
+	}
+}
+
+// Close stops the Group from accepting new tasks
+// and returns without waiting for them.
+// Tasks that were already handed to a worker keep running.
+// Calling Close on a closed Group has no effect.
+func (g *Group) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return
+	}
+	g.closed = true
+	close(g.closing)
+	go func() {
+		// in may only be closed once no Submit can send on it
+		g.senders.Wait()
+		close(g.in)
+	}()
+}
+
+// Wait closes the Group if it is still open
+// and blocks until every submitted task has finished.
+// Errors returned by tasks are joined into a multierror return value.
+// If a task panics, the panic is caught and returned as an error
+// without halting the other tasks.
+// Wait may be called more than once and from multiple goroutines.
+func (g *Group) Wait() error {
+	g.Close()
+	<-g.done
+	return g.err
+}
+
+// WaitTimeout is like Wait, but it gives up after d,
+// as measured by the Clock set with WithClock.
+// It reports whether every submitted task finished in time
+// along with the error Wait would return;
+// if not, the error is nil, and the tasks keep running,
+// so WaitTimeout or Wait can be called again later.
+func (g *Group) WaitTimeout(d time.Duration) (done bool, err error) {
+	g.Close()
+	t := g.clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-g.done:
+		return true, g.err
+	case <-t.C():
+		return false, nil
+	}
+}
+
+// Shutdown stops the Group gracefully.
+// It closes the Group and waits for the submitted tasks to finish
+// until ctx is done,
+// at which point it cancels the context passed to tasks by SubmitContext
+// and returns ctx.Err() without waiting any longer.
+// The canceled tasks keep running until they observe the cancellation;
+// Wait can be called afterwards to wait for them.
+// If every task finishes in time, Shutdown returns the same error as Wait.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.Close()
+	select {
+	case <-g.done:
+		return g.err
+	case <-ctx.Done():
+		g.cancel()
+		return ctx.Err()
+	}
+}
+
+// Prewarm blocks until every worker of the Group has called the setup function
+// set by WithWorkerSetup, if any,
+// so that the first tasks submitted afterwards
+// do not pay the cost of setting up their workers.
+// A worker that is running a task sets up once it finishes,
+// so Prewarm also waits for any tasks already running.
+// Errors and panics from setup are returned joined into a multierror,
+// and a worker whose setup failed tries again before its first task.
+// Calling Prewarm again has no further effect.
+// Prewarm panics if the Group is closed.
+func (g *Group) Prewarm() error {
+	g.mu.Lock()
+	closed := g.closed
+	g.mu.Unlock()
+	if closed {
+		panic("workgroup: Prewarm called on closed Group")
+	}
+	pw := g.prewarm
+	pw.once.Do(func() { close(pw.start) })
+	pw.wg.Wait()
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	return errors.Join(pw.errs...)
+}
+
+// prewarmer has every worker of a Group call its setup for Prewarm.
+type prewarmer struct {
+	once  sync.Once
+	start chan void // closed by Prewarm
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	errs  []error
+}
+
+// failed records the error or panic from a worker's setup, if any.
+func (pw *prewarmer) failed(err error, pval any) {
+	if pval != nil {
+		err = panicErr(pval)
+	}
+	if err == nil {
+		return
+	}
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	pw.errs = append(pw.errs, err)
+}
@@ -0,0 +1,266 @@
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestGroup(t *testing.T) {
+	var n atomic.Int64
+	errBad := errors.New("bad")
+	g := workgroup.NewGroup(2)
+	for i := 1; i <= 4; i++ {
+		i := int64(i)
+		g.Submit(func() error {
+			n.Add(i)
+			if i == 2 {
+				return errBad
+			}
+			if i == 3 {
+				panic("boom")
+			}
+			return nil
+		})
+	}
+	g.Close()
+	err := g.Wait()
+	if !errors.Is(err, errBad) {
+		t.Fatal(err)
+	}
+	if err.Error() != "bad\npanic: boom" && err.Error() != "panic: boom\nbad" {
+		t.Fatal(err)
+	}
+	if n.Load() != 10 {
+		t.Fatal(n.Load())
+	}
+	if err2 := g.Wait(); err2 != err {
+		t.Fatal(err2)
+	}
+}
+
+func TestGroup_submitAfterClose(t *testing.T) {
+	g := workgroup.NewGroup(1)
+	g.Close()
+	defer func() {
+		if recover() == nil {
+			t.Fatal("should have panicked")
+		}
+	}()
+	g.Submit(func() error { return nil })
+}
+
+func TestGroup_doubleClose(t *testing.T) {
+	errBad := errors.New("bad")
+	g := workgroup.NewGroup(1)
+	g.Submit(func() error { return errBad })
+	g.Close()
+	g.Close()
+	if err := g.Wait(); !errors.Is(err, errBad) {
+		t.Fatal(err)
+	}
+}
+
+func TestGroup_closeWhileSubmitting(t *testing.T) {
+	g := workgroup.NewGroup(1)
+	release := make(chan struct{})
+	g.Submit(func() error {
+		<-release
+		return nil
+	})
+	// The only worker is busy, so this Submit blocks until Close
+	panicked := make(chan bool)
+	go func() {
+		defer func() { panicked <- recover() != nil }()
+		g.Submit(func() error { return nil })
+	}()
+	time.Sleep(10 * time.Millisecond)
+	g.Close() // must not wait for the blocked Submit
+	if !<-panicked {
+		t.Fatal("blocked Submit should panic on Close")
+	}
+	close(release)
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGroup_WaitTimeout(t *testing.T) {
+	g := workgroup.NewGroup(2)
+	release := make(chan struct{})
+	g.Submit(func() error {
+		<-release
+		return errors.New("slow")
+	})
+	for i := 0; i < 2; i++ {
+		if ok, err := g.WaitTimeout(5 * time.Millisecond); ok || err != nil {
+			t.Fatal(err, ok)
+		}
+	}
+	close(release)
+	ok, err := g.WaitTimeout(time.Second)
+	if !ok || err == nil || err.Error() != "slow" {
+		t.Fatal(err, ok)
+	}
+	if err := g.Wait(); err == nil {
+		t.Fatal("completion was consumed")
+	}
+}
+
+func TestGroup_WaitTimeout_clock(t *testing.T) {
+	clock := newFakeClock()
+	g := workgroup.NewGroup(1, workgroup.WithClock(clock))
+	release := make(chan struct{})
+	g.Submit(func() error {
+		<-release
+		return nil
+	})
+	timedOut := make(chan bool)
+	go func() {
+		ok, _ := g.WaitTimeout(time.Hour)
+		timedOut <- !ok
+	}()
+	<-clock.added
+	clock.advance()
+	if !<-timedOut {
+		t.Fatal("finished early")
+	}
+	close(release)
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGroup_Shutdown(t *testing.T) {
+	var n atomic.Int64
+	g := workgroup.NewGroup(2)
+	for i := 0; i < 4; i++ {
+		g.SubmitContext(func(ctx context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			n.Add(1)
+			return nil
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := g.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n.Load() != 4 {
+		t.Fatal(n.Load())
+	}
+}
+
+func TestGroup_Shutdown_forced(t *testing.T) {
+	g := workgroup.NewGroup(2)
+	started := make(chan struct{})
+	g.SubmitContext(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := g.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	// the stuck task was canceled and can now be waited for
+	if err := g.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+}
+
+func TestGroup_Prewarm(t *testing.T) {
+	var setups atomic.Int64
+	conns := make([]string, 3)
+	g := workgroup.NewGroup(3, workgroup.WithWorkerSetup(func(worker int) error {
+		setups.Add(1)
+		time.Sleep(5 * time.Millisecond)
+		conns[worker] = fmt.Sprint("conn ", worker)
+		return nil
+	}))
+	if n := setups.Load(); n != 0 {
+		t.Fatal("setup ran before Prewarm:", n)
+	}
+	if err := g.Prewarm(); err != nil {
+		t.Fatal(err)
+	}
+	if n := setups.Load(); n != 3 {
+		t.Fatal(n)
+	}
+	for i := 0; i < 6; i++ {
+		g.SubmitContext(func(ctx context.Context) error {
+			if conns[workgroup.WorkerID(ctx)] == "" {
+				return errors.New("worker not set up")
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if n := setups.Load(); n != 3 {
+		t.Fatal("setup ran again:", n)
+	}
+}
+
+func TestGroup_Prewarm_fails(t *testing.T) {
+	errSetup := errors.New("no connection")
+	var setups atomic.Int64
+	g := workgroup.NewGroup(2, workgroup.WithWorkerSetup(func(worker int) error {
+		if setups.Add(1) <= 2 {
+			return errSetup
+		}
+		return nil
+	}))
+	if err := g.Prewarm(); !errors.Is(err, errSetup) {
+		t.Fatal(err)
+	}
+	// each worker tries setup again before its first task
+	for i := 0; i < 4; i++ {
+		g.Submit(func() error { return nil })
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if n := setups.Load(); n != 4 {
+		t.Fatal(n)
+	}
+}
+
+func TestWithWorkerSetup_fails(t *testing.T) {
+	errSetup := errors.New("no connection")
+	var setups, runs int
+	errs := workgroup.DoTasksErrors(1, []int{0, 1, 2, 3}, func(int) error {
+		runs++
+		return nil
+	}, workgroup.WithWorkerSetup(func(worker int) error {
+		setups++
+		switch setups {
+		case 1:
+			return errSetup
+		case 2:
+			panic("setup panicked")
+		}
+		return nil
+	}))
+	if !errors.Is(errs[0], errSetup) {
+		t.Fatal(errs[0])
+	}
+	if errs[1] == nil || !strings.Contains(errs[1].Error(), "setup panicked") {
+		t.Fatal(errs[1])
+	}
+	if errs[2] != nil || errs[3] != nil {
+		t.Fatal(errs)
+	}
+	if setups != 3 || runs != 2 {
+		t.Fatal(setups, runs)
+	}
+}
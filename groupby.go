@@ -0,0 +1,53 @@
+package workgroup
+
+import "context"
+
+// DoGroupBy starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes each input as a task,
+// returning the outputs of the tasks that succeeded
+// grouped by the key classify returns for them,
+// such as the status class of an HTTP response.
+// Within a group, outputs are in the order of items,
+// unless WithSortResults is used.
+// classify is called serially, so it needs no locking.
+// Errors returned by a task do not halt execution,
+// but are joined into a multierror return value.
+// If a task panics during execution,
+// the panic will be caught and returned as an error halting further execution,
+// and only the outputs of the tasks that finished before it are grouped.
+func DoGroupBy[Input, Output any, K comparable](n int, items []Input, task Task[Input, Output], classify func(Input, Output) K, opts ...Option) (map[K][]Output, error) {
+	cfg := newConfig(opts)
+	checkOutputType[Output]("WithSortResults", cfg.sortType)
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	outs := make([]Output, len(items))
+	keys := make([]*K, len(items))
+	var errs []error
+	viewInputs(cfg, func(i int) Input { return items[i] })
+	err := do(context.Background(), cfg, n, func(i int) (Output, error) {
+		return task(items[i])
+	}, func(i int, out Output, err error) ([]int, error) {
+		if err != nil {
+			errs = append(errs, err)
+			return nil, nil
+		}
+		k := classify(items[i], out)
+		outs[i], keys[i] = out, &k
+		return nil, nil
+	}, indexes)
+	groups := make(map[K][]Output)
+	for i, k := range keys {
+		if k != nil {
+			groups[*k] = append(groups[*k], outs[i])
+		}
+	}
+	for _, group := range groups {
+		sortResults(cfg, group)
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return groups, cfg.join(errs)
+}
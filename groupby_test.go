@@ -0,0 +1,29 @@
+package workgroup_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoGroupBy(t *testing.T) {
+	codes := []int{200, 404, 201, 500, 204, 503, 0}
+	groups, err := workgroup.DoGroupBy(3, codes, func(code int) (string, error) {
+		if code == 0 {
+			return "", errors.New("no response")
+		}
+		return fmt.Sprint("HTTP ", code), nil
+	}, func(code int, _ string) bool {
+		return code < 400
+	})
+	if err == nil || err.Error() != "no response" {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 ||
+		fmt.Sprint(groups[true]) != "[HTTP 200 HTTP 201 HTTP 204]" ||
+		fmt.Sprint(groups[false]) != "[HTTP 404 HTTP 500 HTTP 503]" {
+		t.Fatal(groups)
+	}
+}
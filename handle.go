@@ -0,0 +1,66 @@
+package workgroup
+
+import "context"
+
+// Handle is a batch of tasks running in the background.
+type Handle struct {
+	cancel context.CancelFunc
+	done   chan void
+	err    error
+}
+
+// Start is like DoTasks,
+// but it returns a Handle as soon as the batch has been launched
+// instead of waiting for it to finish.
+func Start[Input any](n int, items []Input, task func(Input) error, opts ...Option) *Handle {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Handle{
+		cancel: cancel,
+		done:   make(chan void),
+	}
+	go func() {
+		defer cancel()
+		h.err = DoTasksCtx(ctx, n, items, task, opts...)
+		close(h.done)
+	}()
+	return h
+}
+
+// StartDo is like DoContext,
+// but it returns a Handle as soon as the run has been launched
+// instead of waiting for it to finish,
+// so that a caller can stop a long-running crawl with Handle.Cancel
+// without creating a context of its own.
+// Canceling the Handle cancels the context passed to the tasks,
+// and Wait returns context.Canceled
+// once the tasks that were running have been managed.
+func StartDo[Input, Output any](n int, task func(context.Context, Input) (Output, error), manager Manager[Input, Output], initial []Input, opts ...Option) *Handle {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Handle{
+		cancel: cancel,
+		done:   make(chan void),
+	}
+	go func() {
+		defer cancel()
+		h.err = DoContext(ctx, n, task, manager, initial, opts...)
+		close(h.done)
+	}()
+	return h
+}
+
+// Wait blocks until the batch has finished
+// and returns its error as DoTasks or DoContext would.
+// Wait may be called more than once and from multiple goroutines.
+func (h *Handle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// Cancel stops the batch from dispatching any more tasks.
+// Tasks that are already running are allowed to finish
+// (though for StartDo their context is canceled),
+// and the error returned by Wait includes context.Canceled.
+// Calling Cancel more than once or after the batch has finished has no effect.
+func (h *Handle) Cancel() {
+	h.cancel()
+}
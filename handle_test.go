@@ -0,0 +1,94 @@
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestStart(t *testing.T) {
+	var n atomic.Int64
+	errBad := errors.New("bad")
+	h := workgroup.Start(2, []int64{1, 2, 3}, func(delta int64) error {
+		n.Add(delta)
+		if delta == 2 {
+			return errBad
+		}
+		return nil
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := h.Wait(); !errors.Is(err, errBad) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := h.Wait(); !errors.Is(err, errBad) {
+		t.Fatal(err)
+	}
+	if n.Load() != 6 {
+		t.Fatal(n.Load())
+	}
+	h.Cancel()
+	if err := h.Wait(); errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+}
+
+func TestStart_cancel(t *testing.T) {
+	var n atomic.Int64
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := workgroup.Start(1, []int{1, 2, 3}, func(int) error {
+		if n.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+	<-started
+	h.Cancel()
+	close(release)
+	if err := h.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	if n.Load() != 1 {
+		t.Fatal(n.Load())
+	}
+}
+
+func TestStartDo(t *testing.T) {
+	var fetched, interrupted atomic.Int64
+	tenth := make(chan struct{})
+	h := workgroup.StartDo(2, func(ctx context.Context, page int) ([]int, error) {
+		switch n := fetched.Add(1); {
+		case n == 10:
+			close(tenth)
+			fallthrough
+		case n > 10:
+			// hang like a stuck fetch until the crawl is canceled
+			<-ctx.Done()
+			interrupted.Add(1)
+			return nil, ctx.Err()
+		}
+		return []int{2 * page, 2*page + 1}, nil
+	}, func(page int, links []int, err error) ([]int, error) {
+		return links, nil
+	}, []int{1})
+	<-tenth
+	h.Cancel()
+	if err := h.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	if n := fetched.Load(); n < 10 || n > 11 || interrupted.Load() != n-9 {
+		t.Fatal(n, interrupted.Load())
+	}
+}
@@ -0,0 +1,99 @@
+package workgroup
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// WithHeartbeat has a run check its running tasks every interval
+// and call onStuck with the input and running time
+// of each task that has been running for at least threshold,
+// so that a hung task can be logged or canceled
+// instead of silently stalling the batch.
+// A task that stays stuck is reported again at every check.
+// onStuck is called from a goroutine of its own, one task at a time,
+// while the workers keep running.
+// The Input type must match the Input type of the run;
+// if it does not, the run panics before starting any tasks.
+// WithHeartbeat panics if interval is not positive.
+func WithHeartbeat[Input any](interval, threshold time.Duration, onStuck func(in Input, running time.Duration)) Option {
+	if interval <= 0 {
+		panic("workgroup: WithHeartbeat called with interval <= 0")
+	}
+	return func(cfg *config) {
+		cfg.heartbeatType = reflect.TypeOf((*Input)(nil)).Elem()
+		cfg.heartbeat = interval
+		cfg.stuckAfter = threshold
+		cfg.onStuck = func(in any, running time.Duration) {
+			onStuck(in.(Input), running)
+		}
+	}
+}
+
+// liveness tracks the task each worker is running for WithHeartbeat.
+type liveness struct {
+	mu      sync.Mutex
+	tasks   []liveTask
+	stopped chan void
+	exited  chan void
+}
+
+type liveTask struct {
+	in      any
+	started time.Time
+}
+
+func newLiveness(workers int) *liveness {
+	return &liveness{
+		tasks:   make([]liveTask, workers),
+		stopped: make(chan void),
+		exited:  make(chan void),
+	}
+}
+
+func (l *liveness) begin(worker int, in any, now time.Time) {
+	l.mu.Lock()
+	l.tasks[worker] = liveTask{in, now}
+	l.mu.Unlock()
+}
+
+func (l *liveness) end(worker int) {
+	l.mu.Lock()
+	l.tasks[worker] = liveTask{}
+	l.mu.Unlock()
+}
+
+// watch reports stuck tasks every cfg.heartbeat until stop is called.
+func (l *liveness) watch(cfg *config) {
+	defer close(l.exited)
+	t := cfg.clock.NewTimer(cfg.heartbeat)
+	defer t.Stop()
+	var stuck []liveTask
+	for {
+		select {
+		case <-l.stopped:
+			return
+		case now := <-t.C():
+			stuck = stuck[:0]
+			l.mu.Lock()
+			for _, task := range l.tasks {
+				if !task.started.IsZero() && now.Sub(task.started) >= cfg.stuckAfter {
+					stuck = append(stuck, task)
+				}
+			}
+			l.mu.Unlock()
+			for _, task := range stuck {
+				cfg.onStuck(task.in, now.Sub(task.started))
+			}
+			t.Reset(cfg.heartbeat)
+		}
+	}
+}
+
+// stop ends watch and waits for it to return,
+// so that onStuck is never called once the run has returned.
+func (l *liveness) stop() {
+	close(l.stopped)
+	<-l.exited
+}
@@ -0,0 +1,37 @@
+package workgroup_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestWithHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		mu    sync.Mutex
+		stuck = map[string]time.Duration{}
+	)
+	err := workgroup.DoTasksContext(ctx, 3, []string{"fast", "hung", "quick"}, func(ctx context.Context, name string) error {
+		if name == "hung" {
+			// hang until the heartbeat gives up on us
+			<-ctx.Done()
+		}
+		return nil
+	}, workgroup.WithHeartbeat(5*time.Millisecond, 20*time.Millisecond, func(name string, running time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		stuck[name] = running
+		cancel()
+	}))
+	if err == nil {
+		t.Fatal("run was not canceled")
+	}
+	if len(stuck) != 1 || stuck["hung"] < 20*time.Millisecond {
+		t.Fatal(stuck)
+	}
+}
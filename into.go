@@ -0,0 +1,26 @@
+package workgroup
+
+import "context"
+
+// DoTasksInto is like DoTasks, but the output of the task for items[i]
+// is written to out[i], so callers can reuse a result buffer across batches
+// instead of having a new one allocated for each.
+// out[i] is written even if the task fails, in which case it holds
+// whatever output the task returned along with its error.
+// DoTasksInto panics if out and items have different lengths.
+func DoTasksInto[Input, Output any](n int, items []Input, out []Output, task Task[Input, Output], opts ...Option) error {
+	if len(out) != len(items) {
+		panic("workgroup: DoTasksInto called with len(out) != len(items)")
+	}
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	cfg := newConfig(opts)
+	viewInputs(cfg, func(i int) Input { return items[i] })
+	return doTasksContext(context.Background(), cfg, n, indexes, func(_ context.Context, i int) error {
+		var err error
+		out[i], err = task(items[i])
+		return err
+	})
+}
@@ -0,0 +1,46 @@
+package workgroup_test
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksInto(t *testing.T) {
+	out := make([]int, 4)
+	err := workgroup.DoTasksInto(2, []string{"1", "2", "x", "4"}, out, strconv.Atoi)
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(out) != "[1 2 0 4]" {
+		t.Fatal(out)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic")
+		}
+	}()
+	_ = workgroup.DoTasksInto(2, []string{"1"}, out, strconv.Atoi)
+}
+
+func BenchmarkDoTasksInto(b *testing.B) {
+	items := make([]int, 1000)
+	double := func(n int) (int, error) { return 2 * n, nil }
+	b.Run("reused", func(b *testing.B) {
+		b.ReportAllocs()
+		out := make([]int, len(items))
+		for i := 0; i < b.N; i++ {
+			_ = workgroup.DoTasksInto(4, items, out, double)
+		}
+	})
+	b.Run("allocated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			out := make([]int, len(items))
+			_ = workgroup.DoTasksInto(4, items, out, double)
+		}
+	})
+}
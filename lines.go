@@ -0,0 +1,73 @@
+package workgroup
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"runtime"
+)
+
+// DoLines starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes each line read from r as a task,
+// without the trailing end-of-line marker, as split by bufio.ScanLines.
+// A final line without a newline is processed like any other.
+// Lines are read by a single goroutine as workers become free,
+// so only a few lines per worker are held in memory at once,
+// however large r is.
+//
+// DoLines stops reading and halts at the first error,
+// whether returned by a task or met while reading r,
+// and returns that error once the running tasks have finished.
+// A line longer than the limit set by WithMaxLineSize
+// (bufio.MaxScanTokenSize by default) fails with bufio.ErrTooLong.
+// If a task panics during execution,
+// the panic will be caught and returned as an error halting further execution.
+// DoLines panics if n is Unlimited, since the lines are not known up front.
+func DoLines(n int, r io.Reader, task func(line string) error, opts ...Option) error {
+	checkUnlimited("DoLines", n)
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	cfg := newConfig(opts)
+	s := bufio.NewScanner(r)
+	if cfg.maxLineSize > 0 {
+		s.Buffer(nil, cfg.maxLineSize)
+	}
+	read := func(max int) []string {
+		var lines []string
+		for len(lines) < max && s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		return lines
+	}
+	// keep a line queued for each worker beyond the one it is running
+	initial := read(2 * n)
+	if len(initial) == 0 {
+		return s.Err()
+	}
+	return do(context.Background(), cfg, n, func(line string) (void, error) {
+		return void{}, task(line)
+	}, func(_ string, _ void, err error) ([]string, error) {
+		if err != nil {
+			return nil, err
+		}
+		lines := read(1)
+		if len(lines) == 0 {
+			return nil, s.Err()
+		}
+		return lines, nil
+	}, initial)
+}
+
+// WithMaxLineSize sets the length of the longest line DoLines can read,
+// for inputs such as JSON Lines files with very large records.
+// The default is bufio.MaxScanTokenSize.
+// WithMaxLineSize panics if size is less than 1.
+func WithMaxLineSize(size int) Option {
+	if size < 1 {
+		panic("workgroup: WithMaxLineSize called with size < 1")
+	}
+	return func(cfg *config) {
+		cfg.maxLineSize = size
+	}
+}
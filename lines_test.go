@@ -0,0 +1,75 @@
+package workgroup_test
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+	"golang.org/x/exp/slices"
+)
+
+func TestDoLines(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		lines []string
+	)
+	err := workgroup.DoLines(2, strings.NewReader("a\nb\r\n\nc\nd"),
+		func(line string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			lines = append(lines, line)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(lines)
+	// blank lines are kept and the last line needs no newline
+	if strings.Join(lines, ",") != ",a,b,c,d" {
+		t.Fatalf("%q", lines)
+	}
+}
+
+func TestDoLines_error(t *testing.T) {
+	errBad := errors.New("bad line")
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		input.WriteString("ok\n")
+	}
+	input.WriteString("bad\n")
+	for i := 0; i < 1000; i++ {
+		input.WriteString("ok\n")
+	}
+	r := strings.NewReader(input.String())
+	err := workgroup.DoLines(2, r, func(line string) error {
+		if line == "bad" {
+			return errBad
+		}
+		return nil
+	})
+	if !errors.Is(err, errBad) {
+		t.Fatal(err)
+	}
+	// reading stopped soon after the failing line
+	if r.Len() == 0 {
+		t.Fatal("read all of the input")
+	}
+}
+
+func TestDoLines_longLine(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	ignore := func(string) error { return nil }
+	err := workgroup.DoLines(1, strings.NewReader("short\n"+long+"\n"), ignore,
+		workgroup.WithMaxLineSize(50))
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Fatal(err)
+	}
+	err = workgroup.DoLines(1, strings.NewReader("short\n"+long+"\n"), ignore,
+		workgroup.WithMaxLineSize(200))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,54 @@
+package workgroup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+)
+
+// DoTasksLogged is like DoTasks,
+// but each task is given its own buffer to write log output to.
+// When a task completes, its buffered output is copied to out in one piece,
+// so the output of concurrent tasks is never interleaved.
+// The output of a task that panics is copied before the panic halts the run.
+// The first error writing to out is joined into the multierror return value
+// along with the errors returned by tasks,
+// and nothing more is written to out after it.
+func DoTasksLogged[Input any](n int, items []Input, task func(w io.Writer, in Input) error, out io.Writer, opts ...Option) error {
+	type logged struct {
+		in  Input
+		buf *bytes.Buffer
+	}
+	inputs := make([]logged, len(items))
+	for i := range items {
+		inputs[i] = logged{items[i], new(bytes.Buffer)}
+	}
+	var errs []error
+	var werr error
+	cfg := newConfig(opts)
+	cfg.managePanics = true
+	viewInputs(cfg, func(l logged) Input { return l.in })
+	err := do(context.Background(), cfg, n, func(l logged) (void, error) {
+		return void{}, task(l.buf, l.in)
+	}, func(l logged, _ void, err error) ([]logged, error) {
+		if werr == nil {
+			if _, werr = l.buf.WriteTo(out); werr != nil {
+				errs = append(errs, werr)
+			}
+		}
+		l.buf.Reset()
+		var pe *panicError
+		if errors.As(err, &pe) {
+			return nil, pe.err
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return nil, nil
+	}, inputs)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return cfg.join(errs)
+}
@@ -0,0 +1,76 @@
+package workgroup_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksLogged(t *testing.T) {
+	var out bytes.Buffer
+	err := workgroup.DoTasksLogged(4, []int{0, 1, 2, 3, 4, 5, 6, 7},
+		func(w io.Writer, n int) error {
+			for i := 0; i < 3; i++ {
+				fmt.Fprintf(w, "task %d line %d\n", n, i)
+				time.Sleep(time.Millisecond)
+			}
+			return nil
+		}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 24 {
+		t.Fatal(len(lines))
+	}
+	for i := 0; i < len(lines); i += 3 {
+		var task int
+		fmt.Sscanf(lines[i], "task %d", &task)
+		for j := 0; j < 3; j++ {
+			if want := fmt.Sprintf("task %d line %d", task, j); lines[i+j] != want {
+				t.Fatalf("got %q; want %q", lines[i+j], want)
+			}
+		}
+	}
+}
+
+type failWriter struct{ writes int }
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("write failed")
+}
+
+func TestDoTasksLogged_errors(t *testing.T) {
+	var out bytes.Buffer
+	err := workgroup.DoTasksLogged(1, []int{1, 2, 3},
+		func(w io.Writer, n int) error {
+			fmt.Fprintf(w, "task %d\n", n)
+			if n == 2 {
+				panic("boom")
+			}
+			return nil
+		}, &out)
+	if err == nil || err.Error() != "panic: boom" {
+		t.Fatal(err)
+	}
+	if out.String() != "task 1\ntask 2\n" {
+		t.Fatalf("%q", out.String())
+	}
+
+	var fw failWriter
+	err = workgroup.DoTasksLogged(2, []int{1, 2, 3},
+		func(w io.Writer, n int) error {
+			fmt.Fprintf(w, "task %d\n", n)
+			return nil
+		}, &fw)
+	if err == nil || err.Error() != "write failed" || fw.writes != 1 {
+		t.Fatal(err, fw.writes)
+	}
+}
@@ -0,0 +1,33 @@
+package workgroup
+
+// Map calls f concurrently on each element of in
+// with n workers (or GOMAXPROCS workers if n < 1)
+// and returns the results for which f reported true,
+// in the order of in or as sorted by WithSortResults,
+// to normalize and filter a large set of inputs before running the real tasks.
+// If f panics, Map panics with the panic as an error
+// once the calls already running have finished.
+func Map[A, B any](n int, in []A, f func(A) (B, bool), opts ...Option) []B {
+	cfg := newConfig(opts)
+	checkOutputType[B]("WithSortResults", cfg.sortType)
+	type kept struct {
+		b  B
+		ok bool
+	}
+	results := make([]kept, len(in))
+	err := DoTasksInto(n, in, results, func(a A) (kept, error) {
+		b, ok := f(a)
+		return kept{b, ok}, nil
+	}, opts...)
+	if err != nil {
+		panic(err)
+	}
+	out := make([]B, 0, len(in))
+	for _, r := range results {
+		if r.ok {
+			out = append(out, r.b)
+		}
+	}
+	sortResults(cfg, out)
+	return out
+}
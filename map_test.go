@@ -0,0 +1,37 @@
+package workgroup_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestMap(t *testing.T) {
+	emails := []string{" Alice@Example.com", "bob", "CAROL@example.com ", "", "dave@example.com"}
+	normalized := workgroup.Map(3, emails, func(s string) (string, bool) {
+		s = strings.ToLower(strings.TrimSpace(s))
+		return s, strings.Contains(s, "@")
+	})
+	if fmt.Sprint(normalized) != "[alice@example.com carol@example.com dave@example.com]" {
+		t.Fatal(normalized)
+	}
+	if out := workgroup.Map(3, nil, func(int) (int, bool) { return 0, true }); len(out) != 0 {
+		t.Fatal(out)
+	}
+}
+
+func TestMap_panic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || fmt.Sprint(r) != "panic: bad input" {
+			t.Fatal(r)
+		}
+	}()
+	workgroup.Map(2, []int{1, 2, 3}, func(n int) (int, bool) {
+		if n == 2 {
+			panic("bad input")
+		}
+		return n, true
+	})
+}
@@ -0,0 +1,41 @@
+package workgroup
+
+import "context"
+
+// DoMixed is like DoTasks for inputs of uneven cost,
+// such as a mix of small and large files.
+// Each input has a weight, and tasks are only dispatched
+// while the total weight of the running tasks stays within maxInFlightWeight,
+// so that the pool never runs too many heavy tasks at once
+// while light tasks can still fill every worker.
+//
+// Inputs are dispatched in order:
+// a heavy input waits for running tasks to finish until it fits
+// instead of being passed over by lighter inputs behind it, so it is not starved.
+// An input heavier than maxInFlightWeight runs once no other task is running.
+// The weight function is called serially, possibly more than once per input,
+// and should return the same non-negative weight each time for the same input.
+// DoMixed panics if maxInFlightWeight is less than 1.
+func DoMixed[Input any](numWorkers int, maxInFlightWeight int64, weight func(Input) int64, inputs []Input, task func(Input) error, opts ...Option) error {
+	if maxInFlightWeight < 1 {
+		panic("workgroup: DoMixed called with maxInFlightWeight < 1")
+	}
+	cfg := newConfig(opts)
+	cfg.maxWeight = maxInFlightWeight
+	cfg.weight = func(in any) int64 {
+		return weight(in.(Input))
+	}
+	var errs []error
+	err := do(context.Background(), cfg, numWorkers, func(in Input) (void, error) {
+		return void{}, task(in)
+	}, func(_ Input, _ void, err error) ([]Input, error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return nil, nil
+	}, inputs)
+	if err != nil {
+		return err
+	}
+	return cfg.join(errs)
+}
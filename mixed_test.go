@@ -0,0 +1,62 @@
+package workgroup_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoMixed(t *testing.T) {
+	var (
+		mu            sync.Mutex
+		weight, peak  int64
+		running, most int
+		ran           []int64
+	)
+	inputs := []int64{8, 1, 1, 8, 1, 1, 1, 8, 1, 12, 1, 1}
+	err := workgroup.DoMixed(4, 10, func(w int64) int64 { return w }, inputs,
+		func(w int64) error {
+			mu.Lock()
+			weight += w
+			running++
+			if weight > peak {
+				peak = weight
+			}
+			if running > most {
+				most = running
+			}
+			if weight > 10 && running > 1 {
+				t.Errorf("weight %d across %d tasks", weight, running)
+			}
+			ran = append(ran, w)
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			weight -= w
+			running--
+			mu.Unlock()
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the oversized input still ran, alone
+	if peak != 12 || len(ran) != len(inputs) {
+		t.Fatal(peak, ran)
+	}
+	if most < 2 {
+		t.Fatal("light tasks never ran together")
+	}
+}
+
+func TestDoMixed_badWeight(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic")
+		}
+	}()
+	_ = workgroup.DoMixed(1, 0, func(int) int64 { return 1 }, []int{1},
+		func(int) error { return nil })
+}
@@ -0,0 +1,52 @@
+package workgroup
+
+// Source is a weighted slice of inputs for DoMulti.
+type Source[Input any] struct {
+	Weight int
+	Inputs []Input
+}
+
+// DoMulti starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes the inputs of every source as tasks.
+// Inputs are dispatched by weighted round-robin across the sources,
+// so a source with Weight 3 has three inputs dispatched
+// for every one input dispatched from a source with Weight 1.
+// Once a source runs out of inputs, the rest share the workers by weight.
+// A Weight less than 1 is treated as 1.
+// Only dispatch order is affected; tasks may still complete in any order.
+// Errors and panics are handled as in DoTasks.
+func DoMulti[Input any](n int, sources []Source[Input], task func(Input) error, opts ...Option) error {
+	return DoTasks(n, interleave(sources), task, opts...)
+}
+
+// interleave merges the sources using smooth weighted round-robin.
+func interleave[Input any](sources []Source[Input]) []Input {
+	total := 0
+	for _, src := range sources {
+		total += len(src.Inputs)
+	}
+	items := make([]Input, 0, total)
+	next := make([]int, len(sources))
+	current := make([]int, len(sources))
+	for len(items) < total {
+		sum, best := 0, -1
+		for i, src := range sources {
+			if next[i] >= len(src.Inputs) {
+				continue
+			}
+			w := src.Weight
+			if w < 1 {
+				w = 1
+			}
+			sum += w
+			current[i] += w
+			if best == -1 || current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= sum
+		items = append(items, sources[best].Inputs[next[best]])
+		next[best]++
+	}
+	return items
+}
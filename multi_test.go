@@ -0,0 +1,39 @@
+package workgroup_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoMulti(t *testing.T) {
+	high := workgroup.Source[string]{Weight: 3, Inputs: []string{}}
+	low := workgroup.Source[string]{Weight: 1, Inputs: []string{}}
+	for i := 0; i < 30; i++ {
+		high.Inputs = append(high.Inputs, "h")
+		low.Inputs = append(low.Inputs, "l")
+	}
+	var order strings.Builder
+	// With one worker, execution order is dispatch order.
+	err := workgroup.DoMulti(1, []workgroup.Source[string]{high, low},
+		func(s string) error {
+			order.WriteString(s)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := order.String()
+	if len(got) != 60 {
+		t.Fatal(got)
+	}
+	for i := 0; i < 40; i += 4 {
+		if window := got[i : i+4]; strings.Count(window, "h") != 3 {
+			t.Fatalf("window %d: %q", i, window)
+		}
+	}
+	if rest := got[40:]; rest != strings.Repeat("l", 20) {
+		t.Fatal(rest)
+	}
+}
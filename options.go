@@ -0,0 +1,591 @@
+package workgroup
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// Option configures how DoWith and the other Do-family functions
+// that accept options run their tasks.
+type Option func(*config)
+
+type config struct {
+	panicHandler func(recovered any, stack []byte)
+	taskEstimate time.Duration
+	spawn        func(f func())
+	limiter      *Limiter
+	slowStart    time.Duration
+	// acquire is set by WithResourcePool;
+	// it returns the task context carrying the resource and its release
+	acquire func(ctx context.Context) (context.Context, func(), error)
+
+	// managePanics passes task panics to the manager as errors
+	// instead of halting
+	managePanics bool
+	// collectPanics is set by WithCollectPanics
+	collectPanics bool
+	// backlog reports how many completed results the caller is holding;
+	// dispatch pauses while inflight plus backlog fills every worker
+	backlog func() int
+	// onHalt is called with the error halting a run early,
+	// before waiting for the running tasks
+	onHalt func(err error)
+	// abandoned is called with each input left unmanaged by a run that halts,
+	// whether it was queued or running, along with the error that halted it
+	abandoned func(in any, err error)
+
+	skipManagerErrors bool
+	cycleKey          func(in any) any
+	cycleType         reflect.Type
+	onCycle           func(cycle []any)
+	dedupKey          func(in any) any
+	dedupType         reflect.Type
+	spillDir          string
+	spillThreshold    int
+	maxGoroutines     int
+	retryPriority     bool
+	shuffle           *rand.Rand
+	checkpointType    reflect.Type
+	completed         func(in any)
+	isDone            func(in any) bool
+	deferDelay        time.Duration
+	clock             Clock
+	controller        *Controller
+	deliverOnHalt     bool
+
+	// onQueueWait is called at dispatch with how long an item was queued,
+	// if it was queued for at least queueWaitThreshold
+	onQueueWait        func(wait time.Duration)
+	queueWaitThreshold time.Duration
+
+	// maxErrors halts a run after that many task errors if it is positive
+	maxErrors int
+	// maxTasks halts a run after dispatching that many tasks if it is positive
+	maxTasks int
+	// stopCondition is set by WithStopCondition;
+	// it returns the check for a run, which accumulates outputs
+	stopCondition func() func(out any) bool
+	stopType      reflect.Type
+	// errorDedup is set by WithErrorDedup
+	errorDedup bool
+	// workerSetup is called by each worker before its first task
+	workerSetup func(worker int) error
+	// prewarm is set by NewGroup so that Prewarm can set up every worker
+	prewarm *prewarmer
+	// cleanup is called with the error of a run once its workers have exited
+	cleanup func(err error)
+	// trace is set by WithTrace
+	trace *tracer
+	// policy is set by WithFrontierPolicy
+	policy FrontierPolicy
+	// taskCanceler is set by WithTaskCanceler
+	taskCanceler taskTracker
+	// heartbeat is how often WithHeartbeat checks for stuck tasks
+	heartbeat     time.Duration
+	stuckAfter    time.Duration
+	onStuck       func(in any, running time.Duration)
+	heartbeatType reflect.Type
+	// maxPending bounds the queue, which overflows as set by WithOverflow
+	maxPending int
+	overflow   Overflow
+	dropped    *int
+	// tuningReport is set by WithTuningReport
+	tuningReport func(workers int, throughput float64)
+	// sortLess is set by WithSortResults
+	sortLess func(a, b any) bool
+	sortType reflect.Type
+	// memoryPressure and minWorkers are set by WithMemoryPressure
+	memoryPressure func() bool
+	minWorkers     int
+	// schedule is set by WithSchedule
+	schedule []int
+	// observer is set by WithTaskObserver
+	observer *taskObserver
+	// maxLineSize is set by WithMaxLineSize
+	maxLineSize int
+	// slowestN task timings are stored in slowest when a run returns
+	slowestN int
+	slowest  *[]TaskTiming
+	// plan is set by WithDryRun and called instead of running tasks
+	plan     func(in any)
+	planType reflect.Type
+
+	// weight is set by DoMixed to cap the total weight of inflight inputs
+	weight    func(in any) int64
+	maxWeight int64
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		spawn:         func(f func()) { go f() },
+		maxGoroutines: DefaultMaxGoroutines,
+		deferDelay:    time.Second,
+		clock:         systemClock{},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithPanicHandler calls handler with the recovered value and stack trace
+// whenever a task panics.
+// The handler runs in the worker goroutine of the panicking task,
+// after which the panic is converted to an error as usual.
+// The handler must not panic itself:
+// a panic in the handler is not recovered and crashes the program.
+func WithPanicHandler(handler func(recovered any, stack []byte)) Option {
+	return func(cfg *config) {
+		cfg.panicHandler = handler
+	}
+}
+
+// WithSpawn has workers started by calling spawn
+// instead of with a plain go statement.
+// spawn must arrange for f to run in a new goroutine
+// (or on some other goroutine that can block until f returns).
+// It can be used to route workers through a managed goroutine pool
+// or to tag them with pprof labels.
+func WithSpawn(spawn func(f func())) Option {
+	return func(cfg *config) {
+		cfg.spawn = spawn
+	}
+}
+
+// WithSlowStart ramps up the number of tasks running at once,
+// like TCP slow start,
+// to avoid overwhelming a downstream service that needs to warm up.
+// A run starts with a single task at a time
+// and doubles the limit every interval until all of the workers are in use,
+// after which it behaves normally.
+func WithSlowStart(interval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.slowStart = interval
+	}
+}
+
+// WithManagerErrorSkip keeps DoWith running when its manager returns an error.
+// The inputs returned alongside the error are still queued,
+// other pending tasks continue as normal,
+// and the manager errors are joined into the multierror return value
+// once there is nothing left to do.
+func WithManagerErrorSkip() Option {
+	return func(cfg *config) {
+		cfg.skipManagerErrors = true
+	}
+}
+
+// WithCycleDetection has DoWith skip any input
+// whose key matches the key of one of the inputs that led to it,
+// so that a crawl of a cyclic graph does not loop forever.
+// If onCycle is not nil, it is called serially with the keys of each cycle found,
+// from the repeated ancestor down to the skipped input.
+// The Input type must match the Input type of the run;
+// if it does not, DoWith panics before starting any tasks.
+//
+// Unlike deduplication, only the path to each input is considered,
+// so the same input may still be processed along different branches.
+// Every queued input holds the keys of its ancestors,
+// so memory use grows with the depth of the crawl as well as its breadth.
+func WithCycleDetection[Input any, K comparable](key func(Input) K, onCycle func(cycle []K)) Option {
+	return func(cfg *config) {
+		cfg.cycleType = reflect.TypeOf((*Input)(nil)).Elem()
+		cfg.cycleKey = func(in any) any {
+			return key(in.(Input))
+		}
+		cfg.onCycle = nil
+		if onCycle != nil {
+			cfg.onCycle = func(cycle []any) {
+				keys := make([]K, len(cycle))
+				for i := range cycle {
+					keys[i] = cycle[i].(K)
+				}
+				onCycle(keys)
+			}
+		}
+	}
+}
+
+// WithDedup has DoWith skip any input
+// whose key matches the key of an input that was already queued,
+// whether as an initial input or as one returned by the manager,
+// so that the manager does not need to track which inputs it has seen.
+// The key function can normalize inputs
+// so that equivalent inputs are treated as duplicates,
+// for example by trimming trailing slashes from URLs.
+// It is called serially before an input is queued.
+// The Input type must match the Input type of the run;
+// if it does not, DoWith panics before starting any tasks.
+// The keys of every queued input are kept in memory until the run returns.
+func WithDedup[Input any, K comparable](key func(Input) K) Option {
+	return func(cfg *config) {
+		cfg.dedupType = reflect.TypeOf((*Input)(nil)).Elem()
+		cfg.dedupKey = func(in any) any {
+			return key(in.(Input))
+		}
+	}
+}
+
+// WithSortResults has a function that collects outputs into a slice,
+// such as Map, DoTasksQuota, or DoGroupBy (for each group),
+// sort the slice it returns with less, keeping equal outputs in their usual order,
+// for example to order records by timestamp.
+// Only the returned slice is affected, not the order tasks run in.
+// Functions that do not return a slice of outputs ignore it.
+// The Output type must match the Output type of the run;
+// if it does not, the run panics before starting any tasks.
+func WithSortResults[Output any](less func(a, b Output) bool) Option {
+	return func(cfg *config) {
+		cfg.sortType = reflect.TypeOf((*Output)(nil)).Elem()
+		cfg.sortLess = func(a, b any) bool {
+			return less(a.(Output), b.(Output))
+		}
+	}
+}
+
+// sortResults sorts outs if WithSortResults is used.
+func sortResults[Output any](cfg *config, outs []Output) {
+	if cfg.sortLess == nil {
+		return
+	}
+	sort.SliceStable(outs, func(i, j int) bool {
+		return cfg.sortLess(outs[i], outs[j])
+	})
+}
+
+// WithRetryPriority has DoWith treat an input returned by the manager
+// that is equal (see reflect.DeepEqual) to the input it is managing as a retry,
+// and queue it ahead of every other pending input
+// so that transient failures recover quickly
+// instead of waiting behind a large backlog of new work.
+// Retries are queued as is:
+// they are not subject to WithDedup or WithCycleDetection.
+func WithRetryPriority() Option {
+	return func(cfg *config) {
+		cfg.retryPriority = true
+	}
+}
+
+// WithCollectPanics keeps a run going when a task panics.
+// Panics are still recovered as usual,
+// but instead of halting the run,
+// the panic is treated as the error result of its task as a *PanicError.
+// With DoWith it is passed to the manager like any other task error,
+// and with run-all functions such as DoTasks
+// it is joined into the multierror return value along with the other errors,
+// so one panic does not hide the rest of the failures in a batch.
+func WithCollectPanics() Option {
+	return func(cfg *config) {
+		cfg.collectPanics = true
+	}
+}
+
+// WithShuffle dispatches the initial inputs in a random order
+// to spread load more evenly across downstream services
+// when the inputs are sorted in a way that clusters related work together.
+// The inputs are shuffled in a copy, so the caller's slice is not modified.
+// Using the same seed gives the same order each time.
+func WithShuffle(seed int64) Option {
+	return func(cfg *config) {
+		cfg.shuffle = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithCheckpoint makes a run resumable.
+// Before an input is queued, isDone is called with it,
+// and the input is skipped if it reports true.
+// After a task succeeds, completed is called with its input.
+// Both are called serially,
+// so they can record progress in a file or database
+// for isDone to consult when the run is restarted after a crash.
+// The Input type must match the Input type of the run;
+// if it does not, the run panics before starting any tasks.
+func WithCheckpoint[Input any](completed func(Input), isDone func(Input) bool) Option {
+	return func(cfg *config) {
+		cfg.checkpointType = reflect.TypeOf((*Input)(nil)).Elem()
+		cfg.completed = func(in any) {
+			completed(in.(Input))
+		}
+		cfg.isDone = func(in any) bool {
+			return isDone(in.(Input))
+		}
+	}
+}
+
+// WithDryRun previews a run without doing any work.
+// Instead of running the task for an input,
+// the run calls plan with the input as it would have been dispatched
+// and treats the task as having succeeded with a zero output,
+// so DoWith passes the manager a zero output
+// and DoGraph goes on to plan the nodes that depend on it.
+// What a manager returns for zero outputs decides how much of a crawl is planned;
+// often that is only the initial inputs.
+// Plan is called serially.
+// WithCheckpoint does not record planned inputs as completed.
+// The Input type must match the Input type of the run;
+// if it does not, the run panics before starting any tasks.
+func WithDryRun[Input any](plan func(Input)) Option {
+	return func(cfg *config) {
+		cfg.planType = reflect.TypeOf((*Input)(nil)).Elem()
+		cfg.plan = func(in any) {
+			plan(in.(Input))
+		}
+	}
+}
+
+// WithDeferDelay sets how long an input whose task returned Defer
+// waits before it is queued again.
+// The default is one second.
+func WithDeferDelay(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.deferDelay = d
+	}
+}
+
+// WithDeliverOnFailFast changes what happens to the results
+// of tasks that were still running when a run halted early
+// because of a panic or an error returned by the manager.
+// By default, those results are discarded.
+// With this option, the successful ones are still passed to the manager,
+// so that work finished before the failure can be recorded,
+// but anything the manager returns is ignored
+// and the run returns the error that halted it as usual.
+func WithDeliverOnFailFast() Option {
+	return func(cfg *config) {
+		cfg.deliverOnHalt = true
+	}
+}
+
+// WithQueueWait has a run call report with how long a task waited
+// between being queued and being dispatched to a worker,
+// whenever that wait is at least threshold.
+// Long waits mean the pool is too small for the rate at which work arrives,
+// even when each task on its own runs quickly.
+// Inputs queued again after a retry or Defer are timed from when they were requeued.
+// The report function is called serially as tasks are dispatched,
+// so it should return quickly.
+// A threshold of zero reports the wait of every task.
+func WithQueueWait(threshold time.Duration, report func(wait time.Duration)) Option {
+	return func(cfg *config) {
+		cfg.queueWaitThreshold = threshold
+		cfg.onQueueWait = report
+	}
+}
+
+// WithMaxErrors halts a run with ErrMaxErrors
+// once n tasks have failed,
+// rather than working through every input of a batch that is failing wholesale.
+// The error that reaches the limit is still managed as usual,
+// so with functions such as DoTasks,
+// ErrMaxErrors is returned joined with the task errors.
+// Errors from panics collected by WithCollectPanics count towards the limit,
+// but Defer does not.
+// WithMaxErrors panics if n is less than 1.
+func WithMaxErrors(n int) Option {
+	if n < 1 {
+		panic("workgroup: WithMaxErrors called with n < 1")
+	}
+	return func(cfg *config) {
+		cfg.maxErrors = n
+	}
+}
+
+// WithMaxTasks stops a run from dispatching more than n tasks in all,
+// as a safety valve against a manager that returns inputs without end,
+// such as a crawl without WithDedup over a graph with cycles.
+// Retries and inputs queued again after Defer each count as a task.
+// Once n tasks have been dispatched,
+// the results of those still running are managed as usual,
+// and if any inputs are then left to work through,
+// the run halts with ErrMaxTasks.
+// Functions that report an outcome for each input, such as DoTasksErrors,
+// report ErrMaxTasks for each input that was not dispatched.
+// WithMaxTasks panics if n is less than 1.
+func WithMaxTasks(n int) Option {
+	if n < 1 {
+		panic("workgroup: WithMaxTasks called with n < 1")
+	}
+	return func(cfg *config) {
+		cfg.maxTasks = n
+	}
+}
+
+// WithStopCondition has a run keep the outputs of the tasks that succeed
+// and call stop with all of them so far after each one is managed,
+// such as to crawl until 100 matching pages have been found.
+// Once stop reports true, the run halts as if the manager had returned Stop:
+// no more tasks are dispatched, the inputs just returned by the manager
+// are dropped, and the run returns without an error of its own.
+// Functions that report an outcome for each input, such as DoTasksIndexed,
+// report Stop as the error for each input whose task did not finish.
+// stop is called serially and must not keep or modify the slice.
+// The Output type must match the Output type of the run;
+// if it does not, the run panics before starting any tasks.
+func WithStopCondition[Output any](stop func(results []Output) bool) Option {
+	return func(cfg *config) {
+		cfg.stopType = reflect.TypeOf((*Output)(nil)).Elem()
+		cfg.stopCondition = func() func(out any) bool {
+			var results []Output
+			return func(out any) bool {
+				results = append(results, out.(Output))
+				return stop(results)
+			}
+		}
+	}
+}
+
+// WithWorkerSetup has each worker call setup with its index (see WorkerID)
+// before it runs its first task,
+// so that expensive per-worker state, such as a connection
+// or a compiled template, is only built by workers that are used.
+// A worker that is never handed a task never calls setup.
+// To build the state of every worker of a Group up front,
+// before the first task is submitted, call its Prewarm method.
+// If setup returns an error or panics,
+// the task the worker was handed fails with that error or panic
+// without running, as if the task had failed itself,
+// and the worker calls setup again before its next task.
+func WithWorkerSetup(setup func(worker int) error) Option {
+	return func(cfg *config) {
+		cfg.workerSetup = setup
+	}
+}
+
+// WithCleanup has a run call cleanup exactly once,
+// after every task has finished and every worker has exited,
+// to release resources shared by the whole run,
+// such as closing a client or flushing a buffer.
+// It is called however the run ends:
+// when it finishes, when it halts early, when its context is canceled,
+// and even when the manager panics,
+// in which case cleanup is passed the panic as an error
+// before the panic continues.
+// For DoWith and DoContext, cleanup is passed the error the run returns.
+// For functions that collect task errors, such as DoTasks,
+// it is only passed the error that halted the run early, if any,
+// such as a panic, ctx.Err(), or ErrMaxErrors.
+func WithCleanup(cleanup func(err error)) Option {
+	return func(cfg *config) {
+		cfg.cleanup = cleanup
+	}
+}
+
+// WithSchedule is a testing aid for reproducing data races and other
+// ordering bugs in tasks: it hands the k-th task dispatched by a run
+// to the worker schedule[k] (see WorkerID),
+// and the tasks after the last entry to each worker in turn.
+// Tasks are still dispatched in queue order, one at a time,
+// and a task waits for its worker to be free,
+// holding back the tasks after it, rather than going to another worker.
+// So, for example, a schedule of 0, 0, 1 runs the first two tasks
+// one after the other on worker 0 while the third runs alongside them
+// on worker 1, no matter how long each one takes.
+// Which tasks share a worker, and the order each worker runs its tasks in,
+// are then the same on every run,
+// although tasks on different workers still overlap in time
+// as the Go scheduler sees fit.
+// It is meant for tests only, since it gives up load balancing.
+// WithSchedule panics if an entry of schedule is negative,
+// and a run panics if an entry is not the index of one of its workers.
+func WithSchedule(schedule []int) Option {
+	for _, w := range schedule {
+		if w < 0 {
+			panic("workgroup: WithSchedule called with a negative worker")
+		}
+	}
+	schedule = append([]int(nil), schedule...)
+	return func(cfg *config) {
+		cfg.schedule = schedule
+	}
+}
+
+// WithTaskObserver has each worker call started just before it runs a task
+// and finished just after,
+// with how long the task took and the error it returned,
+// so that metrics such as the number of active tasks
+// and a histogram of task durations can be kept
+// for a run or for the lifetime of a Group.
+// A panic is passed to finished as an error.
+// Both functions are called concurrently by the workers,
+// so they must be safe for concurrent use.
+func WithTaskObserver(started func(), finished func(d time.Duration, err error)) Option {
+	return func(cfg *config) {
+		cfg.observer = &taskObserver{started, finished}
+	}
+}
+
+type taskObserver struct {
+	started  func()
+	finished func(d time.Duration, err error)
+}
+
+// WithTaskEstimate tells the Do-family functions
+// that a task takes about d to run.
+// If the context of a run has a deadline,
+// the pool is shrunk to the fewest workers
+// that could work through the initial inputs before the deadline,
+// or to a single worker if no task is expected to finish in time.
+// This is only a heuristic for avoiding wasted work;
+// it does not guarantee that tasks finish before the deadline.
+func WithTaskEstimate(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.taskEstimate = d
+	}
+}
+
+// WithMaxGoroutines sets the ceiling on the number of workers
+// that may be started for Unlimited.
+// The default is DefaultMaxGoroutines.
+func WithMaxGoroutines(ceiling int) Option {
+	return func(cfg *config) {
+		cfg.maxGoroutines = ceiling
+	}
+}
+
+// checkUnlimited panics if n is Unlimited
+// for a function that cannot size its pool from its inputs up front.
+func checkUnlimited(fn string, n int) {
+	if n == Unlimited {
+		panic("workgroup: " + fn + " called with Unlimited")
+	}
+}
+
+// poolSize returns how many workers to start
+// when n workers were requested for a run with the given number of initial tasks.
+func (cfg *config) poolSize(ctx context.Context, n, tasks int) int {
+	if n == Unlimited {
+		n = tasks
+		if tasks > cfg.maxGoroutines {
+			panic(fmt.Sprintf("workgroup: Unlimited would start %d workers, more than the ceiling of %d; raise it with WithMaxGoroutines", tasks, cfg.maxGoroutines))
+		}
+		if n < 1 {
+			n = 1
+		}
+	}
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	if cfg.limiter != nil && cfg.limiter.local != nil && cap(cfg.limiter.local) < n {
+		n = cap(cfg.limiter.local)
+	}
+	deadline, ok := ctx.Deadline()
+	if cfg.taskEstimate <= 0 || !ok {
+		return n
+	}
+	perWorker := int(deadline.Sub(cfg.clock.Now()) / cfg.taskEstimate)
+	if perWorker < 1 {
+		return 1
+	}
+	if needed := (tasks + perWorker - 1) / perWorker; needed < n {
+		n = needed
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
@@ -0,0 +1,801 @@
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+	"golang.org/x/exp/slices"
+)
+
+func TestWithPanicHandler(t *testing.T) {
+	var (
+		recovered any
+		stack     string
+	)
+	err := workgroup.DoTasks(1, []int{1, 2}, func(n int) error {
+		if n == 2 {
+			panic("boom")
+		}
+		return nil
+	}, workgroup.WithPanicHandler(func(v any, b []byte) {
+		recovered, stack = v, string(b)
+	}))
+	if err == nil || err.Error() != "panic: boom" {
+		t.Fatal(err)
+	}
+	if recovered != "boom" {
+		t.Fatal(recovered)
+	}
+	if !strings.Contains(stack, "TestWithPanicHandler") {
+		t.Fatal(stack)
+	}
+}
+
+func TestWithTaskEstimate(t *testing.T) {
+	for _, tc := range []struct {
+		opts []workgroup.Option
+		want int64
+	}{
+		{nil, 6},
+		{[]workgroup.Option{workgroup.WithTaskEstimate(300 * time.Millisecond)}, 2},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		var g gauge
+		err := workgroup.DoTasksContext(ctx, 10, []int{1, 2, 3, 4, 5, 6},
+			func(context.Context, int) error {
+				g.enter()
+				defer g.exit()
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			}, tc.opts...)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := g.peak.Load(); got != tc.want {
+			t.Fatal(got, tc.want)
+		}
+	}
+}
+
+func TestWithSlowStart(t *testing.T) {
+	var (
+		g       gauge
+		mu      sync.Mutex
+		earlyHi int64
+	)
+	start := time.Now()
+	err := workgroup.DoTasks(8, make([]int, 40), func(int) error {
+		g.enter()
+		defer g.exit()
+		if time.Since(start) < 40*time.Millisecond {
+			mu.Lock()
+			if n := g.running.Load(); n > earlyHi {
+				earlyHi = n
+			}
+			mu.Unlock()
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}, workgroup.WithSlowStart(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if earlyHi != 1 {
+		t.Fatal(earlyHi)
+	}
+	if g.peak.Load() < 4 {
+		t.Fatal(g.peak.Load())
+	}
+}
+
+func TestOptions_threaded(t *testing.T) {
+	var spawned atomic.Int64
+	spawn := workgroup.WithSpawn(func(f func()) {
+		spawned.Add(1)
+		go f()
+	})
+	items := []int{1, 2, 3}
+	square := func(n int) (int, error) { return n * n, nil }
+	noop := func(int) error { return nil }
+	runs := map[string]func(){
+		"DoMulti": func() {
+			workgroup.DoMulti(2, []workgroup.Source[int]{{Inputs: items}}, noop, spawn)
+		},
+		"DoReduce": func() {
+			workgroup.DoReduce(2, items, square, func(a, b int) int { return a + b }, 0, spawn)
+		},
+		"DoTasksChan": func() {
+			for range workgroup.DoTasksChan(2, items, square, spawn) {
+			}
+		},
+		"DoTasksChanOrdered": func() {
+			for range workgroup.DoTasksChanOrdered(2, items, square, spawn) {
+			}
+		},
+		"DoTasksErrors": func() {
+			workgroup.DoTasksErrors(2, items, noop, spawn)
+		},
+		"DoTasksUntil": func() {
+			workgroup.DoTasksUntil(2, items, square,
+				func(int, int, error) bool { return false }, spawn)
+		},
+		"Start": func() {
+			workgroup.Start(2, items, noop, spawn).Wait()
+		},
+	}
+	for name, run := range runs {
+		spawned.Store(0)
+		run()
+		if spawned.Load() != 2 {
+			t.Errorf("%s: spawned %d", name, spawned.Load())
+		}
+	}
+}
+
+func TestWithDedup(t *testing.T) {
+	links := map[string][]string{
+		"/":        {"/a.html", "/b.html/", "/A.html/"},
+		"/a.html":  {"/", "/b.html"},
+		"/b.html/": {"/a.html/"},
+	}
+	var visited []string
+	err := workgroup.DoWith(2, func(u string) ([]string, error) {
+		return links[u], nil
+	}, func(u string, found []string, err error) ([]string, error) {
+		visited = append(visited, u)
+		return found, err
+	}, []string{"/", "/"},
+		workgroup.WithDedup(func(u string) string {
+			return strings.ToLower(strings.TrimSuffix(u, "/"))
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(visited)
+	if fmt.Sprint(visited) != "[/ /a.html /b.html/]" {
+		t.Fatal(visited)
+	}
+}
+
+func TestWithDedup_indexed(t *testing.T) {
+	// functions that run by index still dedup on the caller's items
+	var ran atomic.Int64
+	errs := workgroup.DoTasksErrors(2, []int{10, 10, 20}, func(int) error {
+		ran.Add(1)
+		return nil
+	}, workgroup.WithDedup(func(n int) int { return n }))
+	if ran.Load() != 2 || len(errs) != 3 {
+		t.Fatal(ran.Load(), errs)
+	}
+	outs, err := workgroup.DoGroupBy(2, []string{"a", "A", "b"},
+		func(s string) (string, error) { return s, nil },
+		func(_, s string) bool { return s == "b" },
+		workgroup.WithDedup(strings.ToLower))
+	if err != nil || len(outs[false]) != 1 || len(outs[true]) != 1 {
+		t.Fatal(outs, err)
+	}
+}
+
+func TestWithMaxGoroutines(t *testing.T) {
+	var g gauge
+	task := func(int) error {
+		g.enter()
+		time.Sleep(10 * time.Millisecond)
+		g.exit()
+		return nil
+	}
+	err := workgroup.DoTasks(workgroup.Unlimited, make([]int, 20), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak := g.peak.Load(); peak != 20 {
+		t.Fatal(peak)
+	}
+
+	err = workgroup.DoTasks(workgroup.Unlimited, make([]int, 20), task,
+		workgroup.WithMaxGoroutines(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		r := recover()
+		if fmt.Sprint(r) != "workgroup: Unlimited would start 21 workers, more than the ceiling of 20; raise it with WithMaxGoroutines" {
+			t.Fatal(r)
+		}
+	}()
+	_ = workgroup.DoTasks(workgroup.Unlimited, make([]int, 21), task,
+		workgroup.WithMaxGoroutines(20))
+	t.Fatal("no panic")
+}
+
+func TestUnlimited(t *testing.T) {
+	var g gauge
+	task := func(int) error {
+		g.enter()
+		time.Sleep(10 * time.Millisecond)
+		g.exit()
+		return nil
+	}
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+	err := workgroup.DoTasksByKey(workgroup.Unlimited,
+		func(n int) int { return n }, items, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak := g.peak.Load(); peak != 20 {
+		t.Fatal(peak)
+	}
+	for name, run := range map[string]func(){
+		"workgroup: Unlimited would start 20 workers, more than the ceiling of 10; raise it with WithMaxGoroutines": func() {
+			_ = workgroup.DoBatchesAdaptive(workgroup.Unlimited, time.Second, items,
+				func([]int) error { return nil }, workgroup.WithMaxGoroutines(10))
+		},
+		"workgroup: DoLines called with Unlimited": func() {
+			_ = workgroup.DoLines(workgroup.Unlimited, strings.NewReader("a\n"),
+				func(string) error { return nil })
+		},
+		"workgroup: NewGroup called with Unlimited": func() {
+			workgroup.NewGroup(workgroup.Unlimited)
+		},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); fmt.Sprint(r) != name {
+					t.Error(r)
+				}
+			}()
+			run()
+		}()
+	}
+}
+
+func TestWithRetryPriority(t *testing.T) {
+	for _, tc := range []struct {
+		opts []workgroup.Option
+		ok   func(retry int) bool
+	}{
+		// without priority the retry waits behind everything else
+		{nil, func(retry int) bool { return retry == 6 }},
+		// with priority only an input dispatched
+		// while the first result was being managed can run first
+		{[]workgroup.Option{workgroup.WithRetryPriority()}, func(retry int) bool { return retry <= 2 }},
+	} {
+		var order []int
+		retried := false
+		err := workgroup.DoWith(1, func(n int) (int, error) {
+			return n, nil
+		}, func(n, _ int, err error) ([]int, error) {
+			order = append(order, n)
+			if n == 1 && !retried {
+				retried = true
+				return []int{10, 1}, nil
+			}
+			return nil, nil
+		}, []int{1, 2, 3, 4, 5}, tc.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		retry := slices.Index(order[1:], 1) + 1
+		if !tc.ok(retry) {
+			t.Fatal(order)
+		}
+	}
+}
+
+func TestWithCollectPanics(t *testing.T) {
+	errBad := errors.New("bad")
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	err := workgroup.DoTasks(4, items, func(n int) error {
+		switch n {
+		case 10, 90:
+			panic(fmt.Sprint("boom ", n))
+		case 50:
+			return errBad
+		}
+		return nil
+	}, workgroup.WithCollectPanics())
+	if !errors.Is(err, errBad) {
+		t.Fatal(err)
+	}
+	var panics []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var pe *workgroup.PanicError
+		if errors.As(err, &pe) {
+			if len(pe.Stack) == 0 {
+				t.Error("missing stack")
+			}
+			panics = append(panics, pe.Error())
+		}
+	}
+	slices.Sort(panics)
+	if fmt.Sprint(panics) != "[panic: boom 10 panic: boom 90]" {
+		t.Fatal(err)
+	}
+}
+
+func TestWithShuffle(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+	run := func() []int {
+		var order []int
+		err := workgroup.DoWith(1, func(n int) (int, error) {
+			return n, nil
+		}, func(n, _ int, _ error) ([]int, error) {
+			order = append(order, n)
+			return nil, nil
+		}, items, workgroup.WithShuffle(42))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return order
+	}
+	order := run()
+	if !slices.IsSorted(items) {
+		t.Fatal(items)
+	}
+	if slices.IsSorted(order) || len(order) != len(items) {
+		t.Fatal(order)
+	}
+	if again := run(); !slices.Equal(order, again) {
+		t.Fatal(order, again)
+	}
+}
+
+func TestWithCheckpoint(t *testing.T) {
+	done := map[int]bool{}
+	checkpoint := workgroup.WithCheckpoint(
+		func(n int) { done[n] = true },
+		func(n int) bool { return done[n] },
+	)
+	items := []int{1, 2, 3, 4, 5, 6}
+	var mu sync.Mutex
+	var ran []int
+	task := func(n int) error {
+		mu.Lock()
+		ran = append(ran, n)
+		mu.Unlock()
+		if n == 4 {
+			return errors.New("crashed")
+		}
+		return nil
+	}
+	if err := workgroup.DoTasks(3, items, task, checkpoint); err == nil {
+		t.Fatal("no error")
+	}
+	if len(ran) != 6 || len(done) != 5 || done[4] {
+		t.Fatal(ran, done)
+	}
+	// restart: only the input that did not complete runs again
+	ran = nil
+	_ = workgroup.DoTasks(3, items, task, checkpoint)
+	if fmt.Sprint(ran) != "[4]" {
+		t.Fatal(ran)
+	}
+}
+
+func TestWithCheckpoint_indexed(t *testing.T) {
+	// functions that run by index still checkpoint the caller's items
+	done := map[string]bool{"b": true}
+	checkpoint := workgroup.WithCheckpoint(
+		func(s string) { done[s] = true },
+		func(s string) bool { return done[s] },
+	)
+	outs, errs := workgroup.DoTasksIndexed(2, []string{"a", "b", "c"},
+		func(s string) (string, error) { return s + s, nil }, checkpoint)
+	if fmt.Sprint(outs) != "map[0:aa 2:cc]" || len(errs) != 0 {
+		t.Fatal(outs, errs)
+	}
+	if len(done) != 3 {
+		t.Fatal(done)
+	}
+	out := make([]string, 3)
+	if err := workgroup.DoTasksInto(2, []string{"a", "d", "b"}, out,
+		func(s string) (string, error) { return s, nil }, checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(out) != "[ d ]" {
+		t.Fatalf("%q", out)
+	}
+}
+
+func TestWithDeliverOnFailFast(t *testing.T) {
+	for _, deliver := range []bool{false, true} {
+		var opts []workgroup.Option
+		if deliver {
+			opts = append(opts, workgroup.WithDeliverOnFailFast())
+		}
+		var started sync.WaitGroup
+		started.Add(3)
+		var recorded []string
+		err := workgroup.DoWith(3, func(u string) (string, error) {
+			// make sure every page is being fetched before the failure
+			started.Done()
+			started.Wait()
+			if u == "/bad" {
+				return "", errors.New("fatal")
+			}
+			time.Sleep(10 * time.Millisecond)
+			return "page " + u, nil
+		}, func(u, page string, err error) ([]string, error) {
+			if err != nil {
+				return nil, err
+			}
+			recorded = append(recorded, page)
+			return []string{"/more"}, nil
+		}, []string{"/a", "/bad", "/b"}, opts...)
+		if err == nil || err.Error() != "fatal" {
+			t.Fatal(err)
+		}
+		slices.Sort(recorded)
+		want := "[]"
+		if deliver {
+			want = "[page /a page /b]"
+		}
+		if fmt.Sprint(recorded) != want {
+			t.Fatal(deliver, recorded)
+		}
+	}
+}
+
+func TestWithQueueWait(t *testing.T) {
+	const threshold = 30 * time.Millisecond
+	var waits []time.Duration
+	err := workgroup.DoTasks(2, []int{1, 2, 3, 4, 5, 6, 7, 8},
+		func(int) error {
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		},
+		workgroup.WithQueueWait(threshold, func(wait time.Duration) {
+			waits = append(waits, wait)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first two tasks start right away,
+	// and the last ones wait behind at least two rounds of slow tasks
+	if len(waits) == 0 || len(waits) > 6 {
+		t.Fatal(waits)
+	}
+	for _, wait := range waits {
+		if wait < threshold {
+			t.Fatal(waits)
+		}
+	}
+}
+
+func TestWithMaxTasks(t *testing.T) {
+	links := map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+	}
+	var fetched []string
+	err := workgroup.DoWith(2, func(page string) ([]string, error) {
+		return links[page], nil
+	}, func(page string, out []string, err error) ([]string, error) {
+		fetched = append(fetched, page)
+		return out, err
+	}, []string{"a"}, workgroup.WithMaxTasks(10))
+	if !errors.Is(err, workgroup.ErrMaxTasks) {
+		t.Fatal(err)
+	}
+	if len(fetched) != 10 {
+		t.Fatal(fetched)
+	}
+	// a run that finishes within the cap is not an error
+	err = workgroup.DoTasks(2, []int{1, 2, 3}, func(int) error { return nil },
+		workgroup.WithMaxTasks(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// inputs that were not dispatched are not reported as successes
+	errs := workgroup.DoTasksErrors(2, []int{1, 2, 3, 4, 5},
+		func(int) error { return nil }, workgroup.WithMaxTasks(3))
+	if fmt.Sprint(errs) != "[<nil> <nil> <nil> workgroup: too many tasks workgroup: too many tasks]" {
+		t.Fatal(errs)
+	}
+	outs, ierrs := workgroup.DoTasksIndexed(2, []int{1, 2, 3, 4, 5},
+		func(n int) (int, error) { return n, nil }, workgroup.WithMaxTasks(3))
+	if len(outs) != 3 || len(ierrs) != 2 || !errors.Is(ierrs[4], workgroup.ErrMaxTasks) {
+		t.Fatal(outs, ierrs)
+	}
+}
+
+func TestWithMaxErrors(t *testing.T) {
+	ran := 0
+	err := workgroup.DoTasks(1, []int{1, 2, 3, 4, 5, 6},
+		func(n int) error {
+			ran++
+			if n%2 == 0 {
+				return fmt.Errorf("bad %d", n)
+			}
+			return nil
+		}, workgroup.WithMaxErrors(2))
+	if !errors.Is(err, workgroup.ErrMaxErrors) {
+		t.Fatal(err)
+	}
+	// the errors that reached the limit are returned too
+	if !strings.Contains(err.Error(), "bad 2") || !strings.Contains(err.Error(), "bad 4") {
+		t.Fatal(err)
+	}
+	if ran > 5 {
+		t.Fatal(ran)
+	}
+}
+
+func TestWithDryRun(t *testing.T) {
+	var planned, completed []string
+	err := workgroup.DoTasks(2, []string{"/a", "/b", "/c"},
+		func(page string) error {
+			t.Errorf("fetched %s", page)
+			return nil
+		},
+		workgroup.WithDryRun(func(page string) {
+			planned = append(planned, page)
+		}),
+		workgroup.WithCheckpoint(func(page string) {
+			completed = append(completed, page)
+		}, func(page string) bool {
+			return page == "/b"
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the plan skips what the checkpoint already has, but records nothing new
+	if fmt.Sprint(planned) != "[/a /c]" || len(completed) != 0 {
+		t.Fatal(planned, completed)
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("no panic for mismatched input type")
+		}
+	}()
+	_ = workgroup.DoTasks(1, []int{1}, func(int) error { return nil },
+		workgroup.WithDryRun(func(string) {}))
+}
+
+func TestWithErrorDedup(t *testing.T) {
+	errUnavailable := errors.New("service unavailable")
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i
+	}
+	task := func(n int) error {
+		switch {
+		case n%10 == 0:
+			return fmt.Errorf("bad input %d", n)
+		case n%2 == 0:
+			return errUnavailable
+		}
+		return nil
+	}
+	err := workgroup.DoTasks(4, items, task, workgroup.WithErrorDedup())
+	if !errors.Is(err, errUnavailable) {
+		t.Fatal(err)
+	}
+	lines := strings.Split(err.Error(), "\n")
+	slices.Sort(lines)
+	want := []string{
+		"bad input 0", "bad input 10", "bad input 20", "bad input 30", "bad input 40",
+		"service unavailable (occurred 20 times)",
+	}
+	if !slices.Equal(lines, want) {
+		t.Fatalf("%q", lines)
+	}
+	// without the option every error is listed
+	err = workgroup.DoTasks(4, items, task)
+	if n := strings.Count(err.Error(), "service unavailable"); n != 20 {
+		t.Fatal(n)
+	}
+}
+
+func TestWithCleanup(t *testing.T) {
+	errBad := errors.New("bad")
+	double := func(n int) (int, error) { return 2 * n, nil }
+	for _, tc := range []struct {
+		name    string
+		manager workgroup.Manager[int, int]
+		cancel  bool
+		want    error
+	}{
+		{"success", func(int, int, error) ([]int, error) { return nil, nil }, false, nil},
+		{"error", func(int, int, error) ([]int, error) { return nil, errBad }, false, errBad},
+		{"canceled", func(int, int, error) ([]int, error) { return nil, nil }, true, context.Canceled},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancel {
+				cancel()
+			}
+			var calls []error
+			err := workgroup.DoContext(ctx, 2, func(_ context.Context, n int) (int, error) {
+				return double(n)
+			}, tc.manager, []int{1, 2, 3},
+				workgroup.WithCleanup(func(err error) {
+					calls = append(calls, err)
+				}))
+			if !errors.Is(err, tc.want) || len(calls) != 1 || !errors.Is(calls[0], tc.want) {
+				t.Fatal(err, calls)
+			}
+		})
+	}
+	t.Run("manager panic", func(t *testing.T) {
+		var calls []error
+		defer func() {
+			if r := recover(); r == nil || len(calls) != 1 || calls[0].Error() != "panic: manager blew up" {
+				t.Fatal(r, calls)
+			}
+		}()
+		_ = workgroup.DoWith(2, double, func(int, int, error) ([]int, error) {
+			panic("manager blew up")
+		}, []int{1, 2, 3}, workgroup.WithCleanup(func(err error) {
+			calls = append(calls, err)
+		}))
+	})
+}
+
+func TestWithTaskObserver(t *testing.T) {
+	var (
+		mu               sync.Mutex
+		active, most     int
+		finished, failed int
+		total            time.Duration
+	)
+	g := workgroup.NewGroup(2, workgroup.WithTaskObserver(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if active++; active > most {
+			most = active
+		}
+	}, func(d time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		active--
+		finished++
+		total += d
+		if err != nil {
+			failed++
+		}
+	}))
+	for i := 0; i < 6; i++ {
+		i := i
+		g.Submit(func() error {
+			time.Sleep(5 * time.Millisecond)
+			switch i {
+			case 1:
+				return errors.New("bad")
+			case 2:
+				panic("worse")
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	if finished != 6 || failed != 2 || active != 0 || most != 2 || total < 30*time.Millisecond {
+		t.Fatal(finished, failed, active, most, total)
+	}
+}
+
+func TestWithSchedule(t *testing.T) {
+	schedule := []int{2, 2, 0, 1, 0}
+	want := "[[2 4 6] [3] [0 1 5]]"
+	for run := 0; run < 5; run++ {
+		var mu sync.Mutex
+		ran := make([][]int, 3)
+		err := workgroup.DoTasksContext(context.Background(), 3, []int{0, 1, 2, 3, 4, 5, 6}, func(ctx context.Context, n int) error {
+			// vary the timing so that only the schedule decides the order
+			time.Sleep(time.Duration((n*7+run*3)%5) * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			w := workgroup.WorkerID(ctx)
+			ran[w] = append(ran[w], n)
+			return nil
+		}, workgroup.WithSchedule(schedule))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(ran); got != want {
+			t.Fatalf("run %d: got %s; want %s", run, got, want)
+		}
+	}
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "worker 3") {
+			t.Fatal(r)
+		}
+	}()
+	_ = workgroup.DoTasks(3, []int{1}, func(int) error { return nil }, workgroup.WithSchedule([]int{0, 3}))
+}
+
+func TestWithSortResults(t *testing.T) {
+	type event struct {
+		Name string
+		At   time.Time
+	}
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	offsets := []int{5, 3, 9, 1, 7, 3}
+	byTime := workgroup.WithSortResults(func(a, b event) bool { return a.At.Before(b.At) })
+	events := workgroup.Map(3, offsets, func(n int) (event, bool) {
+		return event{fmt.Sprint("e", n), base.Add(time.Duration(n) * time.Hour)}, n != 9
+	}, byTime)
+	if !sort.SliceIsSorted(events, func(i, j int) bool { return events[i].At.Before(events[j].At) }) || len(events) != 5 {
+		t.Fatal(events)
+	}
+
+	outs, err := workgroup.DoTasksQuota(3, 100, offsets, func(n int) ([]event, error) {
+		return []event{{"a", base.Add(time.Duration(n) * time.Minute)}, {"b", base.Add(-time.Duration(n) * time.Minute)}}, nil
+	}, byTime)
+	if err != nil || len(outs) != 12 ||
+		!sort.SliceIsSorted(outs, func(i, j int) bool { return outs[i].At.Before(outs[j].At) }) {
+		t.Fatal(err, outs)
+	}
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "WithSortResults") {
+			t.Fatal(r)
+		}
+	}()
+	workgroup.Map(3, offsets, func(n int) (int, bool) { return n, true }, byTime)
+}
+
+func TestWithStopCondition(t *testing.T) {
+	var matches []int
+	tasks := 0
+	err := workgroup.DoWith(2, func(n int) (int, error) {
+		return n, nil
+	}, func(n, _ int, _ error) ([]int, error) {
+		tasks++
+		if n%3 == 0 {
+			matches = append(matches, n)
+		}
+		return []int{n + 1}, nil
+	}, []int{1}, workgroup.WithStopCondition(func(found []int) bool {
+		count := 0
+		for _, n := range found {
+			if n%3 == 0 {
+				count++
+			}
+		}
+		return count == 5
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(matches) != "[3 6 9 12 15]" || tasks != 15 {
+		t.Fatal(matches, tasks)
+	}
+
+	// inputs that did not finish are not reported as successes
+	outs, errs := workgroup.DoTasksIndexed(1, []int{1, 2, 3, 4, 5},
+		func(n int) (int, error) { return n, nil },
+		workgroup.WithStopCondition(func(found []int) bool { return len(found) == 2 }))
+	if len(outs)+len(errs) != 5 || len(errs) < 2 || !errors.Is(errs[4], workgroup.Stop) {
+		t.Fatal(outs, errs)
+	}
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "WithStopCondition") {
+			t.Fatal(r)
+		}
+	}()
+	_ = workgroup.DoTasks(1, []int{1}, func(int) error { return nil },
+		workgroup.WithStopCondition(func([]string) bool { return true }))
+}
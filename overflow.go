@@ -0,0 +1,56 @@
+package workgroup
+
+// WithMaxPending bounds how many inputs a run keeps queued
+// waiting for a worker, for crawls whose frontier would otherwise
+// grow faster than it can be worked through.
+// What happens to an input queued while the queue is full,
+// whether an initial input or one returned by the manager,
+// is set by WithOverflow.
+// Inputs queued again after Defer or as retries under WithRetryPriority
+// are not counted against max.
+// WithMaxPending panics if max is less than 1.
+func WithMaxPending(max int) Option {
+	if max < 1 {
+		panic("workgroup: WithMaxPending called with max < 1")
+	}
+	return func(cfg *config) {
+		cfg.maxPending = max
+	}
+}
+
+// Overflow is what a run does with an input queued
+// while its queue is full (see WithMaxPending).
+type Overflow int
+
+const (
+	// OverflowBlock keeps every input.
+	// While the queue is full,
+	// results wait to be managed for as long as a worker is free
+	// to take an input from the queue instead,
+	// so the queue drains before it grows again.
+	// Once every worker is busy, results are managed as usual,
+	// so the queue can still outgrow its bound
+	// by the inputs the manager returns meanwhile.
+	OverflowBlock Overflow = iota
+	// OverflowDropNewest discards the input being queued.
+	OverflowDropNewest
+	// OverflowDropOldest discards the input that has been queued the longest
+	// to make room for the input being queued.
+	// Under a FrontierPriority policy (see WithFrontierPolicy),
+	// it discards the input that would be dispatched last instead.
+	OverflowDropOldest
+)
+
+// WithOverflow sets what a run does with an input queued
+// while its queue is full (see WithMaxPending).
+// The default is OverflowBlock.
+// Dropping inputs keeps memory bounded
+// for best-effort work that need not be complete.
+// If dropped is not nil, the number of inputs discarded
+// is stored in *dropped when the run returns.
+func WithOverflow(strategy Overflow, dropped *int) Option {
+	return func(cfg *config) {
+		cfg.overflow = strategy
+		cfg.dropped = dropped
+	}
+}
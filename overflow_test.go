@@ -0,0 +1,49 @@
+package workgroup_test
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestWithOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		strategy    workgroup.Overflow
+		ran         string
+		wantDropped int
+	}{
+		{"block", workgroup.OverflowBlock, "[0 1 2 3 4 5 6 7 8 9 10]", 0},
+		{"drop newest", workgroup.OverflowDropNewest, "[0 1 2 3]", 7},
+		{"drop oldest", workgroup.OverflowDropOldest, "[0 8 9 10]", 7},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var ran []int
+			dropped := -1
+			err := workgroup.DoWith(1, func(n int) (int, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				ran = append(ran, n)
+				return n, nil
+			}, func(n, _ int, _ error) ([]int, error) {
+				if n != 0 {
+					return nil, nil
+				}
+				return []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, nil
+			}, []int{0},
+				workgroup.WithMaxPending(3),
+				workgroup.WithOverflow(tc.strategy, &dropped))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Ints(ran)
+			if fmt.Sprint(ran) != tc.ran || dropped != tc.wantDropped {
+				t.Fatal(ran, dropped)
+			}
+		})
+	}
+}
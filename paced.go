@@ -0,0 +1,30 @@
+package workgroup
+
+import (
+	"context"
+	"time"
+)
+
+// DoTasksPaced is like DoTasks, but each task is passed a context
+// whose deadline is the end of its time slot:
+// the task for items[i] must finish within (i+1)*slice of the start of the run.
+// A task that overruns its slot sees its context expire,
+// so a stuck task cannot hold up the tasks scheduled after it for long.
+// Pacing is best effort, not hard real time:
+// tasks still wait for a free worker,
+// and a task that ignores its context is not interrupted.
+func DoTasksPaced[Input any](n int, slice time.Duration, items []Input, task func(context.Context, Input) error, opts ...Option) error {
+	cfg := newConfig(opts)
+	start := cfg.clock.Now()
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	viewInputs(cfg, func(i int) Input { return items[i] })
+	return doTasksContext(context.Background(), cfg, n, indexes, func(ctx context.Context, i int) error {
+		deadline := start.Add(time.Duration(i+1) * slice)
+		ctx, cancel := withDeadline(ctx, cfg.clock, deadline)
+		defer cancel()
+		return task(ctx, items[i])
+	})
+}
@@ -0,0 +1,59 @@
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksPaced(t *testing.T) {
+	var mu sync.Mutex
+	expired := map[int]bool{}
+	durations := []time.Duration{0, time.Second, 0}
+	err := workgroup.DoTasksPaced(3, 50*time.Millisecond, []int{0, 1, 2},
+		func(ctx context.Context, i int) error {
+			select {
+			case <-time.After(durations[i]):
+				return nil
+			case <-ctx.Done():
+				mu.Lock()
+				expired[i] = true
+				mu.Unlock()
+				return ctx.Err()
+			}
+		})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	if len(expired) != 1 || !expired[1] {
+		t.Fatal(expired)
+	}
+}
+
+func TestDoTasksPaced_clock(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	done := make(chan error)
+	go func() {
+		done <- workgroup.DoTasksPaced(1, time.Minute, []int{0},
+			func(ctx context.Context, i int) error {
+				deadline, ok := ctx.Deadline()
+				if !ok || !deadline.Equal(start.Add(time.Minute)) {
+					return errors.New("wrong deadline")
+				}
+				<-ctx.Done()
+				return ctx.Err()
+			}, workgroup.WithClock(clock))
+	}()
+	<-clock.added
+	if d := clock.advance(); d != time.Minute {
+		t.Fatal(d)
+	}
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,57 @@
+package workgroup
+
+// DoTasksPerKey starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes each input as a task,
+// running at most perKey tasks at once for inputs with the same key,
+// such as at most two requests at a time to any one host in a crawl.
+// Inputs whose key is at its limit wait without occupying a worker,
+// so the workers stay busy with other keys,
+// and each key's inputs are started in the order of items.
+// Keys are forgotten once they have no running or waiting tasks.
+// Errors returned by a task do not halt execution,
+// but are joined into a multierror return value.
+// If a task panics during execution,
+// the panic will be caught and returned as an error halting further execution.
+// DoTasksPerKey panics if perKey is less than 1.
+func DoTasksPerKey[Input any, K comparable](n, perKey int, key func(Input) K, items []Input, task func(Input) error, opts ...Option) error {
+	if perKey < 1 {
+		panic("workgroup: DoTasksPerKey called with perKey < 1")
+	}
+	active := make(map[K]int)
+	waiting := make(map[K][]Input)
+	var initial []Input
+	for _, in := range items {
+		k := key(in)
+		if active[k] < perKey {
+			active[k]++
+			initial = append(initial, in)
+			continue
+		}
+		waiting[k] = append(waiting[k], in)
+	}
+	var errs []error
+	err := DoWith(n, func(in Input) (void, error) {
+		return void{}, task(in)
+	}, func(in Input, _ void, err error) ([]Input, error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+		k := key(in)
+		if q := waiting[k]; len(q) > 0 {
+			if len(q) == 1 {
+				delete(waiting, k)
+			} else {
+				waiting[k] = q[1:]
+			}
+			return q[:1], nil
+		}
+		if active[k]--; active[k] == 0 {
+			delete(active, k)
+		}
+		return nil, nil
+	}, initial, opts...)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return newConfig(opts).join(errs)
+}
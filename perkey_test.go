@@ -0,0 +1,43 @@
+package workgroup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksPerKey(t *testing.T) {
+	type request struct {
+		host string
+		path int
+	}
+	hosts := map[string]*gauge{"a.example": {}, "b.example": {}, "c.example": {}}
+	var reqs []request
+	for i := 0; i < 10; i++ {
+		for host := range hosts {
+			reqs = append(reqs, request{host, i})
+		}
+	}
+	var total gauge
+	err := workgroup.DoTasksPerKey(6, 2, func(r request) string { return r.host }, reqs,
+		func(r request) error {
+			total.enter()
+			hosts[r.host].enter()
+			time.Sleep(2 * time.Millisecond)
+			hosts[r.host].exit()
+			total.exit()
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for host, g := range hosts {
+		if peak := g.peak.Load(); peak > 2 {
+			t.Fatal(host, peak)
+		}
+	}
+	if peak := total.peak.Load(); peak <= 2 {
+		t.Fatal(peak)
+	}
+}
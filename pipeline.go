@@ -0,0 +1,171 @@
+package workgroup
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// DoPipeline runs each input through two stages with separately sized pools,
+// such as a CPU-bound stage followed by an IO-bound stage,
+// so that neither pool has to be sized for the other's work.
+// Each input is first passed to cpu on one of cpuWorkers workers,
+// and its intermediate value is then handed to io on one of ioWorkers workers.
+// A cpu worker waits for a free io worker before taking its next input,
+// so intermediate values never pile up.
+// DoPipeline returns the outputs in the order of items,
+// with zero values for inputs that failed in either stage.
+// Errors from either stage do not halt execution,
+// but are joined into a multierror return value.
+// If a stage panics, the panic is caught and joined as an error for that input.
+func DoPipeline[Input, Mid, Output any](cpuWorkers, ioWorkers int, items []Input, cpu func(Input) (Mid, error), io func(Mid) (Output, error)) ([]Output, error) {
+	outs := make([]Output, len(items))
+	cpuGroup := NewGroup(cpuWorkers)
+	ioGroup := NewGroup(ioWorkers)
+	for i := range items {
+		i := i
+		cpuGroup.Submit(func() error {
+			mid, err := cpu(items[i])
+			if err != nil {
+				return err
+			}
+			ioGroup.Submit(func() error {
+				out, err := io(mid)
+				if err == nil {
+					outs[i] = out
+				}
+				return err
+			})
+			return nil
+		})
+	}
+	cpuErr := cpuGroup.Wait()
+	ioErr := ioGroup.Wait()
+	return outs, errors.Join(cpuErr, ioErr)
+}
+
+// Pipe is a multistage pipeline under construction
+// whose last stage produces values of type T.
+// Start one with Pipeline, add stages with Pipe.Stage or Then,
+// and start it with Pipe.Run.
+type Pipe[T any] struct {
+	// start launches the pipeline up to this stage
+	// and returns the channel its values come out of,
+	// which is closed once the stage is done.
+	start func(ctx context.Context, fail func(error)) <-chan T
+}
+
+// Pipeline starts a multistage pipeline that feeds items to its first stage.
+// Unlike DoPipeline, each stage runs on a pool of its own
+// connected to the next stage by a channel
+// holding at most as many values as the next stage has workers,
+// so values stream through the stages without piling up between them.
+func Pipeline[T any](items []T) *Pipe[T] {
+	return &Pipe[T]{func(ctx context.Context, _ func(error)) <-chan T {
+		ch := make(chan T)
+		go func() {
+			defer close(ch)
+			for _, item := range items {
+				select {
+				case ch <- item:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return ch
+	}}
+}
+
+// Stage adds a stage that passes each value through task
+// on n concurrent workers (or GOMAXPROCS workers if n < 1).
+// The stage must produce values of the same type it takes;
+// use Then for a stage that changes the type.
+// Stage panics if n is Unlimited, since the values are not known up front.
+func (p *Pipe[T]) Stage(n int, task func(context.Context, T) (T, error)) *Pipe[T] {
+	checkUnlimited("Stage", n)
+	return Then(p, n, task)
+}
+
+// Then adds a stage to p that passes each value through task
+// on n concurrent workers (or GOMAXPROCS workers if n < 1).
+// It is a function rather than a method of Pipe
+// because a method cannot introduce the new type Out.
+// Then panics if n is Unlimited, since the values are not known up front.
+func Then[In, Out any](p *Pipe[In], n int, task func(context.Context, In) (Out, error)) *Pipe[Out] {
+	checkUnlimited("Then", n)
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return &Pipe[Out]{func(ctx context.Context, fail func(error)) <-chan Out {
+		in := p.start(ctx, fail)
+		out := make(chan Out, n)
+		var wg sync.WaitGroup
+		wg.Add(n)
+		for i := 0; i < n; i++ {
+			go func() {
+				defer wg.Done()
+				for v := range in {
+					if ctx.Err() != nil {
+						// keep draining so the stage before can finish
+						continue
+					}
+					o, err := runStage(ctx, task, v)
+					if err != nil {
+						fail(err)
+						continue
+					}
+					select {
+					case out <- o:
+					case <-ctx.Done():
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(out)
+		}()
+		return out
+	}}
+}
+
+// runStage calls task, returning a panic as an error.
+func runStage[In, Out any](ctx context.Context, task func(context.Context, In) (Out, error), in In) (out Out, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{r, debug.Stack()}
+		}
+	}()
+	return task(ctx, in)
+}
+
+// Run starts every stage of p and returns the values
+// that come out of its last stage, in the order they come out,
+// which may differ from the order of the items fed into it.
+// The first error returned by any stage, or the first panic,
+// cancels the context passed to every stage and stops the pipeline,
+// and Run returns that error once every stage has finished.
+// If ctx is done first, Run returns ctx.Err().
+func (p *Pipe[T]) Run(ctx context.Context) ([]T, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var once sync.Once
+	var failure error
+	fail := func(err error) {
+		once.Do(func() {
+			failure = err
+			cancel(err)
+		})
+	}
+	var outs []T
+	for v := range p.start(ctx, fail) {
+		outs = append(outs, v)
+	}
+	if failure != nil {
+		return outs, failure
+	}
+	return outs, ctxErr(ctx)
+}
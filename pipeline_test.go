@@ -0,0 +1,82 @@
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoPipeline(t *testing.T) {
+	var cpu, io gauge
+	items := []string{"1", "2", "x", "4", "5", "6", "7", "8"}
+	outs, err := workgroup.DoPipeline(2, 4, items,
+		func(s string) (int, error) {
+			cpu.enter()
+			defer cpu.exit()
+			time.Sleep(time.Millisecond)
+			return strconv.Atoi(s)
+		}, func(n int) (string, error) {
+			io.enter()
+			defer io.exit()
+			time.Sleep(10 * time.Millisecond)
+			return fmt.Sprint(n * n), nil
+		})
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%q", outs) != `["1" "4" "" "16" "25" "36" "49" "64"]` {
+		t.Fatal(outs)
+	}
+	if peak := cpu.peak.Load(); peak > 2 {
+		t.Fatal(peak)
+	}
+	if peak := io.peak.Load(); peak <= 2 || peak > 4 {
+		t.Fatal(peak)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	parse := func(_ context.Context, s string) (int, error) { return strconv.Atoi(s) }
+	square := func(_ context.Context, n int) (int, error) { return n * n, nil }
+	format := func(_ context.Context, n int) (string, error) { return fmt.Sprintf("<%d>", n), nil }
+	build := func(items []string) *workgroup.Pipe[string] {
+		p := workgroup.Then(workgroup.Pipeline(items), 2, parse)
+		return workgroup.Then(p.Stage(3, square), 1, format)
+	}
+
+	outs, err := build([]string{"1", "2", "3", "4", "5"}).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(outs)
+	if fmt.Sprint(outs) != "[<16> <1> <25> <4> <9>]" {
+		t.Fatal(outs)
+	}
+
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+	items[10] = "x"
+	outs, err = build(items).Run(context.Background())
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatal(err)
+	}
+	if len(outs) >= 999 {
+		t.Fatal("pipeline kept going after an error", len(outs))
+	}
+
+	_, err = workgroup.Pipeline([]int{1, 2}).Stage(1, func(context.Context, int) (int, error) {
+		panic("boom")
+	}).Run(context.Background())
+	var pe *workgroup.PanicError
+	if !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,40 @@
+package workgroup
+
+import (
+	"runtime"
+	"time"
+)
+
+// pressureInterval is how often a run calls the check of WithMemoryPressure.
+const pressureInterval = 250 * time.Millisecond
+
+// WithMemoryPressure has a run call check every quarter second
+// and halve how many tasks it may have going at once,
+// down to minWorkers, each time check reports true,
+// then double it again, back up to the size of the pool,
+// each time check reports false,
+// trading throughput for stability when memory runs short.
+// As with Controller.SetWorkers, the pool itself keeps its size,
+// and a task that is already running is not interrupted.
+// See HeapAbove for a check based on the Go heap.
+// WithMemoryPressure panics if minWorkers is less than 1.
+func WithMemoryPressure(check func() bool, minWorkers int) Option {
+	if minWorkers < 1 {
+		panic("workgroup: WithMemoryPressure called with minWorkers < 1")
+	}
+	return func(cfg *config) {
+		cfg.memoryPressure = check
+		cfg.minWorkers = minWorkers
+	}
+}
+
+// HeapAbove returns a check for WithMemoryPressure
+// that reports whether the Go heap holds more than limit bytes,
+// according to runtime.ReadMemStats.
+func HeapAbove(limit uint64) func() bool {
+	return func() bool {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return m.HeapAlloc > limit
+	}
+}
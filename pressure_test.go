@@ -0,0 +1,86 @@
+package workgroup_test
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestWithMemoryPressure(t *testing.T) {
+	clock := newFakeClock()
+	var (
+		pressure  atomic.Bool
+		low       atomic.Bool
+		g         gauge
+		lowPeak   atomic.Int64
+		lowStarts atomic.Int64
+	)
+	pressure.Store(true)
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- workgroup.DoTasks(4, make([]int, 16), func(int) error {
+			g.enter()
+			defer g.exit()
+			if low.Load() {
+				lowStarts.Add(1)
+				if n := g.running.Load(); n > lowPeak.Load() {
+					lowPeak.Store(n)
+				}
+			}
+			<-release
+			return nil
+		}, workgroup.WithClock(clock), workgroup.WithMemoryPressure(pressure.Load, 1))
+	}()
+	waitFor := func(n *atomic.Int64, want int64) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for n.Load() != want {
+			if time.Now().After(deadline) {
+				t.Fatal(n.Load())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitFor(&g.running, 4)
+	<-clock.added
+	// under pressure, 4 workers halve to 2, then 1
+	for i := 0; i < 2; i++ {
+		clock.advance()
+		<-clock.added
+	}
+	low.Store(true)
+	for i := 0; i < 7; i++ {
+		release <- struct{}{}
+	}
+	waitFor(&lowStarts, 4)
+	if lowPeak.Load() != 1 {
+		t.Fatal(lowPeak.Load())
+	}
+	// once the pressure is gone, 1 doubles to 2, then 4
+	low.Store(false)
+	pressure.Store(false)
+	for i := 0; i < 2; i++ {
+		clock.advance()
+		<-clock.added
+	}
+	waitFor(&g.running, 4)
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeapAbove(t *testing.T) {
+	if workgroup.HeapAbove(1 << 62)() {
+		t.Fatal("heap over an exabyte")
+	}
+	buf := make([]byte, 1<<20)
+	if !workgroup.HeapAbove(0)() {
+		t.Fatal("empty heap")
+	}
+	runtime.KeepAlive(buf)
+}
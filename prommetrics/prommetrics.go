@@ -0,0 +1,80 @@
+// Package prommetrics exports metrics about the tasks run by workgroup
+// as Prometheus collectors with consistent names.
+// It is kept apart from workgroup
+// so that only programs that use it depend on the Prometheus client.
+package prommetrics
+
+import (
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics counts the tasks run by the runs and Groups it is passed to.
+// Create one with New, pass its Option to a run or to workgroup.NewGroup,
+// and register its collectors:
+//
+//	m := prommetrics.New("crawler")
+//	prometheus.MustRegister(m.Collectors()...)
+//	g := workgroup.NewGroup(8, m.Option())
+//
+// The same Metrics may be shared by several runs,
+// in which case their tasks are counted together.
+type Metrics struct {
+	tasks    prometheus.Counter
+	failed   prometheus.Counter
+	duration prometheus.Histogram
+	active   prometheus.Gauge
+}
+
+// New returns Metrics whose names are prefixed with namespace:
+//
+//   - namespace_tasks_total counts the tasks that finished
+//   - namespace_tasks_failed_total counts the tasks that returned an error or panicked
+//   - namespace_task_duration_seconds is a histogram of how long tasks took
+//   - namespace_workers_active is the number of workers running a task
+//
+// If namespace is empty, the names have no prefix.
+func New(namespace string) *Metrics {
+	return &Metrics{
+		tasks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tasks_total",
+			Help:      "Number of tasks that finished.",
+		}),
+		failed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tasks_failed_total",
+			Help:      "Number of tasks that returned an error or panicked.",
+		}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "task_duration_seconds",
+			Help:      "How long tasks took to run.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		active: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "workers_active",
+			Help:      "Number of workers running a task.",
+		}),
+	}
+}
+
+// Option returns an option that records the tasks of a run or Group in m.
+func (m *Metrics) Option() workgroup.Option {
+	return workgroup.WithTaskObserver(m.active.Inc, func(d time.Duration, err error) {
+		m.active.Dec()
+		m.tasks.Inc()
+		if err != nil {
+			m.failed.Inc()
+		}
+		m.duration.Observe(d.Seconds())
+	})
+}
+
+// Collectors returns the collectors of m for registering with Prometheus.
+func (m *Metrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.tasks, m.failed, m.duration, m.active}
+}
@@ -0,0 +1,48 @@
+package prommetrics_test
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+	"github.com/carlmjohnson/workgroup/prommetrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestMetrics(t *testing.T) {
+	m := prommetrics.New("crawler")
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m.Collectors()...)
+
+	g := workgroup.NewGroup(2, m.Option())
+	for i := 0; i < 5; i++ {
+		i := i
+		g.Submit(func() error {
+			if i%2 == 1 {
+				return errors.New("bad")
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err == nil {
+		t.Fatal("expected errors")
+	}
+
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		"crawler_tasks_total 5",
+		"crawler_tasks_failed_total 2",
+		"crawler_task_duration_seconds_count 5",
+		"crawler_workers_active 0",
+	} {
+		if !strings.Contains(string(body), want+"\n") {
+			t.Fatalf("missing %q in:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package workgroup
+
+import "context"
+
+// DoTasksQuota starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes each input as a task producing any number of outputs,
+// until at least quota outputs have been collected.
+// Once the quota is reached, no further tasks are dispatched,
+// and the outputs of tasks that are still running are discarded
+// once they finish.
+// The outputs are returned in the order their tasks completed,
+// unless WithSortResults is used.
+// Because every output of the task that reaches the quota is kept,
+// the result may exceed quota by up to the size of that task's output.
+// If the quota is reached, the error is nil;
+// otherwise the errors returned by tasks are joined into a multierror.
+// If a task panics during execution,
+// the panic will be caught and returned as an error halting further execution.
+func DoTasksQuota[Input, Output any](n, quota int, items []Input, task func(Input) ([]Output, error), opts ...Option) ([]Output, error) {
+	cfg := newConfig(opts)
+	checkOutputType[Output]("WithSortResults", cfg.sortType)
+	var outs []Output
+	var errs []error
+	if quota < 1 {
+		return outs, nil
+	}
+	err := do(context.Background(), cfg, n, task, func(_ Input, o []Output, err error) ([]Input, error) {
+		if err != nil {
+			errs = append(errs, err)
+			return nil, nil
+		}
+		outs = append(outs, o...)
+		if len(outs) >= quota {
+			return nil, Stop
+		}
+		return nil, nil
+	}, items)
+	sortResults(cfg, outs)
+	if err != nil {
+		return outs, err
+	}
+	if len(outs) >= quota {
+		return outs, nil
+	}
+	return outs, cfg.join(errs)
+}
@@ -0,0 +1,47 @@
+package workgroup_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksQuota(t *testing.T) {
+	pages := make([]int, 20)
+	for i := range pages {
+		pages[i] = i
+	}
+	ran := 0
+	records, err := workgroup.DoTasksQuota(1, 10, pages, func(page int) ([]string, error) {
+		ran++
+		if page%5 == 4 {
+			return nil, errors.New("page unavailable")
+		}
+		// each page holds three records
+		return []string{
+			fmt.Sprint(page, "a"), fmt.Sprint(page, "b"), fmt.Sprint(page, "c"),
+		}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the quota is met by the fourth page, overshooting by two
+	if len(records) != 12 {
+		t.Fatal(records)
+	}
+	if ran > 6 {
+		t.Fatal(ran)
+	}
+
+	records, err = workgroup.DoTasksQuota(2, 100, pages[:4], func(page int) ([]string, error) {
+		if page == 2 {
+			return nil, errors.New("page unavailable")
+		}
+		return []string{fmt.Sprint(page)}, nil
+	})
+	if len(records) != 3 || err == nil || err.Error() != "page unavailable" {
+		t.Fatal(records, err)
+	}
+}
@@ -0,0 +1,42 @@
+package workgroup
+
+// DoReduce starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// that run task on each input
+// and folds each successful output into an accumulator that starts as init.
+// Calls to reduce are serialized, so reduce needs no locking,
+// but outputs are folded in the order their tasks complete.
+// Errors returned by a task do not halt execution,
+// but are joined into a multierror return value.
+// If a task panics during execution,
+// the panic will be caught and returned as an error halting further execution.
+func DoReduce[Input, Output, Acc any](n int, items []Input, task Task[Input, Output], reduce func(Acc, Output) Acc, init Acc, opts ...Option) (Acc, error) {
+	return DoTasksFold(n, items, task, reduce, init, nil, opts...)
+}
+
+// DoTasksFold is DoReduce for online aggregation:
+// after each output is folded into the accumulator,
+// onStep, if it is not nil, is called with the running value,
+// such as a running total or histogram,
+// so that it can be published for a progress display while the batch runs.
+// fold and onStep are called serially and need no locking,
+// but if Acc holds references, such as a map,
+// onStep must copy what it keeps rather than keep acc itself.
+func DoTasksFold[Input, Output, Acc any](n int, items []Input, task Task[Input, Output], fold func(Acc, Output) Acc, init Acc, onStep func(acc Acc), opts ...Option) (Acc, error) {
+	acc := init
+	var errs []error
+	err := DoWith(n, task, func(_ Input, out Output, err error) ([]Input, error) {
+		if err != nil {
+			errs = append(errs, err)
+			return nil, nil
+		}
+		acc = fold(acc, out)
+		if onStep != nil {
+			onStep(acc)
+		}
+		return nil, nil
+	}, items, opts...)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return acc, newConfig(opts).join(errs)
+}
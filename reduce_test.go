@@ -0,0 +1,45 @@
+package workgroup_test
+
+import (
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoReduce(t *testing.T) {
+	sum, err := workgroup.DoReduce(3, []int{1, 2, 3, 4, 5},
+		func(n int) (int, error) {
+			return n * n, nil
+		}, func(acc, sq int) int {
+			return acc + sq
+		}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 55 {
+		t.Fatal(sum)
+	}
+}
+
+func TestDoTasksFold(t *testing.T) {
+	var running []int
+	total, err := workgroup.DoTasksFold(4, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+		func(n int) (int, error) {
+			return n * n, nil
+		}, func(sum, sq int) int {
+			return sum + sq
+		}, 0, func(sum int) {
+			running = append(running, sum)
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 385 || len(running) != 10 || running[9] != 385 {
+		t.Fatal(total, running)
+	}
+	for i := 1; i < len(running); i++ {
+		if running[i] <= running[i-1] {
+			t.Fatal(running)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package workgroup
+
+import "context"
+
+type resourceKey struct{}
+
+// WithResourcePool makes each worker acquire a resource,
+// such as a database connection, before running a task
+// and release it once the task returns.
+// The task gets the resource from its context with Resource,
+// so it is meant for functions that pass tasks a context,
+// such as DoContext and DoTasksContext.
+//
+// The acquire function should block until a resource is free,
+// which keeps the number of running tasks within what the pool can supply
+// even when there are more workers than resources.
+// Sizing the run's workers to the pool avoids leaving workers idle,
+// but is not needed for correctness.
+// If acquire returns an error, the task is not run
+// and the error is its result.
+func WithResourcePool[R any](acquire func() (R, error), release func(R)) Option {
+	return func(cfg *config) {
+		cfg.acquire = func(ctx context.Context) (context.Context, func(), error) {
+			r, err := acquire()
+			if err != nil {
+				return ctx, nil, err
+			}
+			return context.WithValue(ctx, resourceKey{}, r), func() { release(r) }, nil
+		}
+	}
+}
+
+// Resource returns the resource acquired for the task that was passed ctx
+// by WithResourcePool.
+// It reports false if ctx carries no resource of type R.
+func Resource[R any](ctx context.Context) (R, bool) {
+	r, ok := ctx.Value(resourceKey{}).(R)
+	return r, ok
+}
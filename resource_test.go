@@ -0,0 +1,84 @@
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+type fakeConn struct{ id int }
+
+func TestWithResourcePool(t *testing.T) {
+	// a pool of two connections shared by four workers
+	pool := make(chan *fakeConn, 2)
+	pool <- &fakeConn{1}
+	pool <- &fakeConn{2}
+	var (
+		mu          sync.Mutex
+		inUse, peak int
+		used        = map[int]bool{}
+	)
+	acquire := func() (*fakeConn, error) {
+		c := <-pool
+		mu.Lock()
+		defer mu.Unlock()
+		if used[c.id] {
+			t.Errorf("conn %d handed out twice", c.id)
+		}
+		used[c.id] = true
+		inUse++
+		if inUse > peak {
+			peak = inUse
+		}
+		return c, nil
+	}
+	release := func(c *fakeConn) {
+		mu.Lock()
+		used[c.id] = false
+		inUse--
+		mu.Unlock()
+		pool <- c
+	}
+	err := workgroup.DoTasksContext(context.Background(), 4, []int{1, 2, 3, 4, 5, 6, 7, 8},
+		func(ctx context.Context, _ int) error {
+			c, ok := workgroup.Resource[*fakeConn](ctx)
+			if !ok || c == nil {
+				return errors.New("no conn")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !used[c.id] {
+				t.Errorf("conn %d used after release", c.id)
+			}
+			return nil
+		},
+		workgroup.WithResourcePool(acquire, release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak > 2 || inUse != 0 || len(pool) != 2 {
+		t.Fatal(peak, inUse, len(pool))
+	}
+	if _, ok := workgroup.Resource[*fakeConn](context.Background()); ok {
+		t.Fatal("resource outside of a task")
+	}
+}
+
+func TestWithResourcePool_acquireError(t *testing.T) {
+	errExhausted := errors.New("pool exhausted")
+	ran := false
+	err := workgroup.DoTasksContext(context.Background(), 1, []int{1},
+		func(ctx context.Context, _ int) error {
+			ran = true
+			return nil
+		},
+		workgroup.WithResourcePool(
+			func() (*fakeConn, error) { return nil, errExhausted },
+			func(*fakeConn) { t.Error("released a resource that was not acquired") }))
+	if !errors.Is(err, errExhausted) || ran {
+		t.Fatal(err, ran)
+	}
+}
@@ -0,0 +1,140 @@
+package workgroup
+
+import (
+	"context"
+	"time"
+)
+
+// RetryBudget wraps task so that a failed attempt is retried
+// with exponential backoff starting at base,
+// until an attempt succeeds or the time spent on the input,
+// counting every attempt and every backoff, would exceed budget.
+// It returns the output and error of the last attempt.
+// An attempt that is already running is not interrupted when the budget runs out,
+// but no backoff is started that would end past it.
+// If ctx is done during a backoff, the wrapped task returns ctx.Err()
+// without making another attempt.
+// Time is measured by clock, or by the system clock if clock is nil.
+func RetryBudget[Input, Output any](clock Clock, budget, base time.Duration, task func(context.Context, Input) (Output, error)) func(context.Context, Input) (Output, error) {
+	clock = orSystemClock(clock)
+	return func(ctx context.Context, in Input) (Output, error) {
+		deadline := clock.Now().Add(budget)
+		delay := base
+		for {
+			out, err := task(ctx, in)
+			if err == nil || deadline.Sub(clock.Now()) < delay {
+				return out, err
+			}
+			if err := sleep(ctx, clock, delay); err != nil {
+				return out, err
+			}
+			delay *= 2
+		}
+	}
+}
+
+// DefaultRetryDelay is the delay before the first retry by RetryWithDelay
+// when the error does not suggest one.
+// Each later retry waits twice as long as the one before.
+const DefaultRetryDelay = 100 * time.Millisecond
+
+// RetryWithDelay wraps task so that a failed attempt is retried,
+// making at most attempts attempts in all.
+// Before each retry, delayFrom is called with the error;
+// if it reports ok, RetryWithDelay waits the delay it returns,
+// such as one parsed from the Retry-After header of an HTTP response,
+// and otherwise it falls back to exponential backoff
+// starting at DefaultRetryDelay.
+// It returns the output and error of the last attempt.
+// If ctx is done during a delay, the wrapped task returns ctx.Err()
+// without making another attempt.
+// Time is measured by clock, or by the system clock if clock is nil.
+func RetryWithDelay[Input, Output any](clock Clock, attempts int, delayFrom func(err error) (time.Duration, bool), task func(context.Context, Input) (Output, error)) func(context.Context, Input) (Output, error) {
+	return retry(orSystemClock(clock), attempts, nil, delayFrom, task)
+}
+
+// RetryIf wraps task so that an attempt that fails
+// with an error for which shouldRetry reports true is retried,
+// making at most attempts attempts in all,
+// with exponential backoff starting at DefaultRetryDelay.
+// An error for which shouldRetry reports false,
+// such as a 404 response or a validation failure,
+// is returned right away without spending the remaining attempts.
+// It returns the output and error of the last attempt.
+// If ctx is done during a backoff, the wrapped task returns ctx.Err()
+// without making another attempt.
+// Time is measured by clock, or by the system clock if clock is nil.
+func RetryIf[Input, Output any](clock Clock, attempts int, shouldRetry func(err error) bool, task func(context.Context, Input) (Output, error)) func(context.Context, Input) (Output, error) {
+	return retry(orSystemClock(clock), attempts, shouldRetry, nil, task)
+}
+
+// retry makes up to attempts attempts at task,
+// stopping early if shouldRetry is non-nil and reports false.
+// It waits the delay from delayFrom, if non-nil and ok, between attempts,
+// and otherwise backs off exponentially from DefaultRetryDelay.
+func retry[Input, Output any](clock Clock, attempts int, shouldRetry func(error) bool, delayFrom func(error) (time.Duration, bool), task func(context.Context, Input) (Output, error)) func(context.Context, Input) (Output, error) {
+	return func(ctx context.Context, in Input) (Output, error) {
+		backoff := DefaultRetryDelay
+		for i := 1; ; i++ {
+			out, err := task(ctx, in)
+			if err == nil || i >= attempts || (shouldRetry != nil && !shouldRetry(err)) {
+				return out, err
+			}
+			var delay time.Duration
+			ok := false
+			if delayFrom != nil {
+				delay, ok = delayFrom(err)
+			}
+			if !ok {
+				delay = backoff
+				backoff *= 2
+			}
+			if err := sleep(ctx, clock, delay); err != nil {
+				return out, err
+			}
+		}
+	}
+}
+
+// sleep waits for d to pass on clock
+// and returns ctx.Err() if ctx is done first.
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	t := clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return ctx.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DoTasksRetryBatch runs a batch as DoTasksErrors does
+// and then runs it again with only the inputs whose tasks failed,
+// making at most attempts rounds in all,
+// for systems that are only eventually consistent,
+// where a failure may go away once the rest of the batch has landed.
+// A round starts once every task of the round before it has finished.
+// DoTasksRetryBatch returns nil if every input eventually succeeds,
+// and otherwise the errors of the last round joined into a multierror.
+// Panics are caught and retried like any other error.
+// DoTasksRetryBatch panics if attempts is less than 1.
+func DoTasksRetryBatch[Input any](n, attempts int, items []Input, task func(Input) error, opts ...Option) error {
+	if attempts < 1 {
+		panic("workgroup: DoTasksRetryBatch called with attempts < 1")
+	}
+	var failed []error
+	for i := 0; i < attempts && len(items) > 0; i++ {
+		errs := DoTasksErrors(n, items, task, opts...)
+		var retry []Input
+		failed = failed[:0]
+		for j, err := range errs {
+			if err != nil {
+				retry = append(retry, items[j])
+				failed = append(failed, err)
+			}
+		}
+		items = retry
+	}
+	return newConfig(opts).join(failed)
+}
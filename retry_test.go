@@ -0,0 +1,192 @@
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestRetryBudget(t *testing.T) {
+	attempts := 0
+	task := workgroup.RetryBudget(nil, 100*time.Millisecond, 10*time.Millisecond,
+		func(_ context.Context, n int) (int, error) {
+			attempts++
+			return 0, fmt.Errorf("attempt %d failed", attempts)
+		})
+	start := time.Now()
+	_, err := task(context.Background(), 1)
+	elapsed := time.Since(start)
+	// backoffs of 10, 20, and 40ms fit in the budget, but 80ms more does not
+	if attempts != 4 || err == nil || err.Error() != "attempt 4 failed" {
+		t.Fatal(attempts, err)
+	}
+	if elapsed > 100*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+
+	attempts = 0
+	task = workgroup.RetryBudget(nil, time.Second, time.Millisecond,
+		func(_ context.Context, n int) (int, error) {
+			attempts++
+			if attempts < 3 {
+				return 0, errors.New("flaky")
+			}
+			return n * 2, nil
+		})
+	if out, err := task(context.Background(), 21); out != 42 || err != nil || attempts != 3 {
+		t.Fatal(out, err, attempts)
+	}
+}
+
+func TestRetryBudget_cancel(t *testing.T) {
+	clock := newFakeClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	task := workgroup.RetryBudget(clock, time.Hour, time.Minute,
+		func(context.Context, int) (int, error) {
+			attempts++
+			return 0, errors.New("unavailable")
+		})
+	done := make(chan error)
+	go func() {
+		_, err := task(ctx, 1)
+		done <- err
+	}()
+	// cancel during the first backoff instead of waiting it out
+	<-clock.added
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) || attempts != 1 {
+		t.Fatal(err, attempts)
+	}
+}
+
+type retryAfterError struct {
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string { return "429 Too Many Requests" }
+
+func TestRetryWithDelay(t *testing.T) {
+	clock := newFakeClock()
+	attempts := 0
+	task := workgroup.RetryWithDelay(clock, 4,
+		func(err error) (time.Duration, bool) {
+			var rae *retryAfterError
+			if errors.As(err, &rae) {
+				return rae.after, true
+			}
+			return 0, false
+		},
+		func(_ context.Context, n int) (int, error) {
+			attempts++
+			switch attempts {
+			case 1:
+				return 0, &retryAfterError{30 * time.Second}
+			case 2, 3:
+				return 0, errors.New("connection reset")
+			}
+			return n, nil
+		})
+	done := make(chan error)
+	go func() {
+		_, err := task(context.Background(), 1)
+		done <- err
+	}()
+	var waited []time.Duration
+	for {
+		select {
+		case err := <-done:
+			if err != nil || attempts != 4 {
+				t.Fatal(err, attempts)
+			}
+			if fmt.Sprint(waited) != "[30s 100ms 200ms]" {
+				t.Fatal(waited)
+			}
+			return
+		case <-clock.added:
+			waited = append(waited, clock.advance())
+		}
+	}
+}
+
+func TestRetryIf(t *testing.T) {
+	errNotFound := errors.New("404 Not Found")
+	errReset := errors.New("connection reset")
+	clock := newFakeClock()
+	// every retry is immediately due
+	go func() {
+		for range clock.added {
+			clock.advance()
+		}
+	}()
+	attempts := map[string]int{}
+	task := workgroup.RetryIf(clock, 3,
+		func(err error) bool { return errors.Is(err, errReset) },
+		func(_ context.Context, page string) (string, error) {
+			attempts[page]++
+			switch page {
+			case "/missing":
+				return "", errNotFound
+			case "/flaky":
+				if attempts[page] < 3 {
+					return "", errReset
+				}
+			case "/down":
+				return "", errReset
+			}
+			return "ok " + page, nil
+		})
+	for _, tc := range []struct {
+		page     string
+		want     error
+		attempts int
+	}{
+		{"/", nil, 1},
+		{"/missing", errNotFound, 1},
+		{"/flaky", nil, 3},
+		{"/down", errReset, 3},
+	} {
+		_, err := task(context.Background(), tc.page)
+		if !errors.Is(err, tc.want) || attempts[tc.page] != tc.attempts {
+			t.Fatal(tc.page, err, attempts[tc.page])
+		}
+	}
+}
+
+func TestDoTasksRetryBatch(t *testing.T) {
+	// how many attempts it takes for each record to become visible
+	visibleAfter := map[string]int{"a": 1, "b": 2, "c": 1, "d": 3, "e": 2}
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	rounds := make([]int, 3)
+	task := func(id string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		rounds[attempts[id]]++
+		attempts[id]++
+		if attempts[id] < visibleAfter[id] {
+			return fmt.Errorf("%s not visible yet", id)
+		}
+		return nil
+	}
+	err := workgroup.DoTasksRetryBatch(2, 3, []string{"a", "b", "c", "d", "e"}, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the failures are run again
+	if fmt.Sprint(rounds) != "[5 3 1]" {
+		t.Fatal(rounds)
+	}
+
+	attempts = map[string]int{}
+	rounds = make([]int, 3)
+	err = workgroup.DoTasksRetryBatch(2, 2, []string{"a", "d"}, task)
+	if err == nil || err.Error() != "d not visible yet" {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,38 @@
+package workgroup
+
+import "time"
+
+// ScalingResult is the outcome of running a batch at one worker count
+// in a ScalingReport.
+type ScalingResult struct {
+	Workers    int
+	Elapsed    time.Duration
+	Throughput float64 // tasks completed per second
+	Err        error   // the error returned by DoTasks, if any
+}
+
+// ScalingReport runs the same batch of items through DoTasks
+// once for each of workerCounts in turn
+// and reports how long each run took,
+// to help find the point where adding workers stops paying off.
+// The task runs for every item at every worker count,
+// so it should be safe to repeat.
+// Measurements come from single runs on a possibly busy machine;
+// treat them as a guide rather than a benchmark.
+// The options are passed along to each run of DoTasks.
+func ScalingReport[Input any](task func(Input) error, items []Input, workerCounts []int, opts ...Option) []ScalingResult {
+	clock := newConfig(opts).clock
+	results := make([]ScalingResult, 0, len(workerCounts))
+	for _, n := range workerCounts {
+		start := clock.Now()
+		err := DoTasks(n, items, task, opts...)
+		elapsed := clock.Now().Sub(start)
+		results = append(results, ScalingResult{
+			Workers:    n,
+			Elapsed:    elapsed,
+			Throughput: float64(len(items)) / elapsed.Seconds(),
+			Err:        err,
+		})
+	}
+	return results
+}
@@ -0,0 +1,26 @@
+package workgroup
+
+import "context"
+
+// DoTasksScratch is like DoTasks, but each worker calls newScratch once,
+// before its first task, and passes every task it runs
+// a pointer to the same scratch value,
+// so that hot tasks can reuse a buffer or an encoder
+// instead of allocating one each time.
+// A scratch value is only ever used by one task at a time,
+// so it needs no locking,
+// but a task should reset whatever state it relies on,
+// since the value still holds whatever the previous task left in it.
+func DoTasksScratch[Input, Scratch any](n int, newScratch func() Scratch, items []Input, task func(*Scratch, Input) error, opts ...Option) error {
+	ctx := context.Background()
+	workers := newConfig(opts).poolSize(ctx, n, len(items))
+	scratches := make([]*Scratch, workers)
+	return DoTasksContext(ctx, workers, items, func(ctx context.Context, in Input) error {
+		w := WorkerID(ctx)
+		if scratches[w] == nil {
+			s := newScratch()
+			scratches[w] = &s
+		}
+		return task(scratches[w], in)
+	}, opts...)
+}
@@ -0,0 +1,71 @@
+package workgroup_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksScratch(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	var created, inUse atomic.Int64
+	var total atomic.Int64
+	err := workgroup.DoTasksScratch(4, func() bytes.Buffer {
+		created.Add(1)
+		return bytes.Buffer{}
+	}, items, func(buf *bytes.Buffer, n int) error {
+		if inUse.Add(1) > 4 {
+			return errors.New("more tasks than workers")
+		}
+		defer inUse.Add(-1)
+		buf.Reset()
+		fmt.Fprintf(buf, "item %d", n)
+		total.Add(int64(buf.Len()))
+		if n == 13 {
+			return errors.New("unlucky")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "unlucky" {
+		t.Fatal(err)
+	}
+	if c := created.Load(); c < 1 || c > 4 {
+		t.Fatal(c)
+	}
+	// "item 0" to "item 9", "item 10" to "item 99"
+	if total.Load() != 10*6+90*7 {
+		t.Fatal(total.Load())
+	}
+}
+
+func BenchmarkDoTasksScratch(b *testing.B) {
+	items := make([]int, 1000)
+	b.Run("scratch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = workgroup.DoTasksScratch(4, func() bytes.Buffer { return bytes.Buffer{} }, items,
+				func(buf *bytes.Buffer, n int) error {
+					buf.Reset()
+					fmt.Fprintf(buf, "item %d", n)
+					return nil
+				})
+		}
+	})
+	b.Run("per task", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = workgroup.DoTasks(4, items, func(n int) error {
+				buf := new(bytes.Buffer)
+				fmt.Fprintf(buf, "item %d", n)
+				return nil
+			})
+		}
+	})
+}
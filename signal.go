@@ -0,0 +1,30 @@
+package workgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// DoTasksSignal is like DoTasksContext,
+// but the context passed to tasks is canceled
+// when the process receives one of sigs
+// (or SIGINT or SIGTERM if no signals are given).
+// The signal handler is removed before DoTasksSignal returns,
+// so a second signal gets the default behavior.
+// If a signal cut the run short, the error matches ErrStopped.
+func DoTasksSignal[Input any](n int, items []Input, task func(context.Context, Input) error, sigs ...os.Signal) error {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), sigs...)
+	defer stop()
+	err := DoTasksContext(ctx, n, items, task)
+	if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+		err = fmt.Errorf("%w: %w", ErrStopped, err)
+	}
+	return err
+}
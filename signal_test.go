@@ -0,0 +1,38 @@
+//go:build unix
+
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksSignal(t *testing.T) {
+	ran := 0
+	err := workgroup.DoTasksSignal(1, []int{0, 1, 2, 3},
+		func(ctx context.Context, i int) error {
+			ran++
+			if i == 0 {
+				if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+					return err
+				}
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+				return errors.New("not canceled")
+			}
+			return nil
+		}, syscall.SIGUSR1)
+	if !errors.Is(err, workgroup.ErrStopped) || !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	if ran != 1 {
+		t.Fatal(ran)
+	}
+}
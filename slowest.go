@@ -0,0 +1,78 @@
+package workgroup
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TaskTiming is how long the task for an input took to run.
+type TaskTiming struct {
+	Input    any
+	Duration time.Duration
+}
+
+// WithSlowest keeps track of the n tasks of a run that took the longest
+// and stores them in *slowest, slowest first, when the run returns,
+// to find the handful of problem inputs in a large batch
+// without keeping the timing of every task.
+// With functions that work through their items by index,
+// such as DoTasksInto and DoTasksIndexed, the Input of a TaskTiming is the index.
+// Only the time spent in the task itself is counted,
+// not the time spent waiting for a worker, a Limiter, or a resource.
+// WithSlowest panics if n is less than 1.
+func WithSlowest(n int, slowest *[]TaskTiming) Option {
+	if n < 1 {
+		panic("workgroup: WithSlowest called with n < 1")
+	}
+	return func(cfg *config) {
+		cfg.slowestN = n
+		cfg.slowest = slowest
+	}
+}
+
+// slowTracker is a bounded min-heap of the slowest task timings,
+// so the fastest of them can be evicted cheaply.
+type slowTracker struct {
+	mu      sync.Mutex
+	n       int
+	timings timingHeap
+}
+
+func (st *slowTracker) add(in any, d time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	switch {
+	case len(st.timings) < st.n:
+		heap.Push(&st.timings, TaskTiming{in, d})
+	case d > st.timings[0].Duration:
+		st.timings[0] = TaskTiming{in, d}
+		heap.Fix(&st.timings, 0)
+	}
+}
+
+// sorted returns the timings, slowest first.
+func (st *slowTracker) sorted() []TaskTiming {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	timings := []TaskTiming(st.timings)
+	sort.Slice(timings, func(i, j int) bool {
+		return timings[i].Duration > timings[j].Duration
+	})
+	return timings
+}
+
+type timingHeap []TaskTiming
+
+func (h timingHeap) Len() int           { return len(h) }
+func (h timingHeap) Less(i, j int) bool { return h[i].Duration < h[j].Duration }
+func (h timingHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *timingHeap) Push(x any)        { *h = append(*h, x.(TaskTiming)) }
+
+func (h *timingHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,31 @@
+package workgroup_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestWithSlowest(t *testing.T) {
+	var slowest []workgroup.TaskTiming
+	err := workgroup.DoTasks(4, []int{3, 1, 8, 5, 2, 7, 4, 6},
+		func(n int) error {
+			time.Sleep(time.Duration(n) * 5 * time.Millisecond)
+			return nil
+		}, workgroup.WithSlowest(3, &slowest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inputs []any
+	for _, timing := range slowest {
+		inputs = append(inputs, timing.Input)
+		if timing.Duration < time.Duration(timing.Input.(int))*5*time.Millisecond {
+			t.Fatal(timing)
+		}
+	}
+	if fmt.Sprint(inputs) != "[8 7 6]" {
+		t.Fatal(slowest)
+	}
+}
@@ -0,0 +1,137 @@
+package workgroup
+
+import (
+	"encoding/gob"
+	"errors"
+	"os"
+)
+
+// DoTasksSpill is like DoTasksInto, but instead of filling a slice,
+// it returns a function that yields the outputs in the order of items,
+// for batch jobs whose outputs together would not fit in memory.
+// Only threshold outputs are held in memory at a time;
+// the rest are written in chunks of threshold outputs
+// to a temporary file in dir
+// (or the default directory for temporary files if dir is empty)
+// and read back as they are yielded.
+// As with DoTasksChanOrdered, an output that completes ahead of
+// the outputs for earlier items is held in memory until those are ready.
+//
+// The returned function has the shape of an iter.Seq2:
+// it calls yield with each output in turn and a nil error,
+// or with the error met reading the outputs back, after which it stops.
+// It can be called only once,
+// and the temporary file is removed when it returns,
+// so callers should always call it, even if they then stop early.
+//
+// The output of every task that ran is yielded,
+// including whatever a failing task returned along with its error.
+// Errors returned by a task do not halt execution,
+// but are joined into a multierror return value.
+// If a task panics during execution,
+// the panic will be caught and returned as an error halting further execution;
+// the outputs of the tasks that never ran are left out.
+// If the outputs cannot be written, DoTasksSpill waits for the running tasks
+// and returns the error with a nil function.
+//
+// Spilled outputs are encoded with encoding/gob,
+// so Output must be a type that gob can encode and decode.
+// DoTasksSpill panics if threshold is less than 1.
+func DoTasksSpill[Input, Output any](n, threshold int, dir string, items []Input, task Task[Input, Output], opts ...Option) (func(yield func(Output, error) bool), error) {
+	if threshold < 1 {
+		panic("workgroup: DoTasksSpill called with threshold < 1")
+	}
+	s := outputSpill[Output]{dir: dir, threshold: threshold}
+	var errs []error
+	var werr error
+	for r := range DoTasksChanOrdered(n, items, task, opts...) {
+		// a panic is sent as a final Result without an output
+		var pe *PanicError
+		if errors.As(r.Err, &pe) {
+			errs = append(errs, r.Err)
+			continue
+		}
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+		if werr == nil {
+			werr = s.add(r.Out)
+		}
+	}
+	if werr != nil {
+		s.remove()
+		return nil, werr
+	}
+	return s.all, newConfig(opts).join(errs)
+}
+
+// outputSpill holds the outputs of DoTasksSpill in order,
+// spilling them to a file in chunks of threshold outputs.
+type outputSpill[Output any] struct {
+	dir       string
+	threshold int
+	file      *os.File
+	enc       *gob.Encoder
+	chunks    int
+	mem       []Output
+}
+
+func (s *outputSpill[Output]) add(out Output) error {
+	s.mem = append(s.mem, out)
+	if len(s.mem) < s.threshold {
+		return nil
+	}
+	if s.file == nil {
+		file, err := os.CreateTemp(s.dir, "workgroup-outputs-*")
+		if err != nil {
+			return err
+		}
+		s.file, s.enc = file, gob.NewEncoder(file)
+	}
+	if err := s.enc.Encode(s.mem); err != nil {
+		return err
+	}
+	s.chunks++
+	s.mem = s.mem[:0]
+	return nil
+}
+
+// all yields the spilled chunks followed by the outputs still in memory.
+func (s *outputSpill[Output]) all(yield func(Output, error) bool) {
+	defer s.remove()
+	if s.file != nil {
+		var zero Output
+		if _, err := s.file.Seek(0, 0); err != nil {
+			yield(zero, err)
+			return
+		}
+		dec := gob.NewDecoder(s.file)
+		var chunk []Output
+		for ; s.chunks > 0; s.chunks-- {
+			chunk = chunk[:0]
+			if err := dec.Decode(&chunk); err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, out := range chunk {
+				if !yield(out, nil) {
+					return
+				}
+			}
+		}
+	}
+	for _, out := range s.mem {
+		if !yield(out, nil) {
+			return
+		}
+	}
+}
+
+func (s *outputSpill[Output]) remove() {
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+		s.file = nil
+	}
+	s.mem = nil
+}
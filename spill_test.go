@@ -0,0 +1,67 @@
+package workgroup_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksSpill(t *testing.T) {
+	dir := t.TempDir()
+	items := make([]int, 25)
+	for i := range items {
+		items[i] = i
+	}
+	outs, err := workgroup.DoTasksSpill(4, 3, dir, items, func(n int) (string, error) {
+		if n == 7 {
+			return "bad", errors.New("seven")
+		}
+		return fmt.Sprint(n * n), nil
+	})
+	if err == nil || err.Error() != "seven" {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatal("outputs never spilled", entries)
+	}
+	var got []string
+	outs(func(out string, err error) bool {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, out)
+		return true
+	})
+	if len(got) != len(items) {
+		t.Fatal(got)
+	}
+	for i, out := range got {
+		want := fmt.Sprint(i * i)
+		if i == 7 {
+			want = "bad"
+		}
+		if out != want {
+			t.Fatal(i, got)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatal(entries)
+	}
+
+	// stopping early still removes the file
+	ints, err := workgroup.DoTasksSpill(2, 2, dir, items, func(n int) (int, error) { return n, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	ints(func(int, error) bool {
+		n++
+		return n < 5
+	})
+	if entries, _ := os.ReadDir(dir); n != 5 || len(entries) != 0 {
+		t.Fatal(n, entries)
+	}
+}
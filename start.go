@@ -1,8 +1,11 @@
 package workgroup
 
 import (
+	"fmt"
 	"runtime"
+	"runtime/debug"
 	"sync"
+	"time"
 )
 
 // result is the type returned by the output channel of Start.
@@ -11,35 +14,80 @@ type result[Input, Output any] struct {
 	Out   Output
 	Err   error
 	Panic any
+	Stack []byte
 }
 
 // start n workers (or GOMAXPROCS workers if n < 1) which consume
 // the in channel, execute task, and send the Result on the out channel.
+// Each worker passes task its own index, from 0 to n-1.
 // Callers should close the in channel to stop the workers from waiting for tasks.
 // The out channel will be closed once the last result has been sent.
-func start[Input, Output any](n int, task Task[Input, Output]) (in chan<- Input, out <-chan result[Input, Output]) {
+func start[Input, Output any](cfg *config, n int, task func(worker int, in Input) (Output, error)) (in chan<- Input, out <-chan result[Input, Output]) {
 	if n < 1 {
 		n = runtime.GOMAXPROCS(0)
 	}
 	inch := make(chan Input)
 	ouch := make(chan result[Input, Output], n)
+	sources := make([]chan Input, n)
+	for i := range sources {
+		sources[i] = inch
+	}
+	if cfg.schedule != nil {
+		for _, w := range cfg.schedule {
+			if w >= n {
+				panic(fmt.Sprintf("workgroup: WithSchedule called with worker %d for a pool of %d workers", w, n))
+			}
+		}
+		for i := range sources {
+			sources[i] = make(chan Input)
+		}
+		go dispatch(cfg.schedule, inch, sources)
+	}
 	var wg sync.WaitGroup
 	wg.Add(n)
 	for i := 0; i < n; i++ {
-		go func() {
+		worker := i
+		src := sources[i]
+		cfg.spawn(func() {
 			defer wg.Done()
-			defer func() {
-				pval := recover()
-				if pval == nil {
-					return
+			ready := cfg.workerSetup == nil
+			var warm <-chan void
+			if cfg.prewarm != nil {
+				warm = cfg.prewarm.start
+				defer func() {
+					if warm != nil {
+						// count this worker as warm for a Prewarm that comes too late
+						cfg.prewarm.wg.Done()
+					}
+				}()
+			}
+			for {
+				select {
+				case inval, ok := <-src:
+					if !ok {
+						return
+					}
+					if !ready {
+						r := setupWorker[Input, Output](cfg, worker, inval)
+						if r.Err != nil || r.Panic != nil {
+							ouch <- r
+							continue
+						}
+						ready = true
+					}
+					ouch <- run(cfg, worker, task, inval)
+				case <-warm:
+					warm = nil
+					if !ready {
+						var zero Input
+						r := setupWorker[Input, Output](cfg, worker, zero)
+						ready = r.Err == nil && r.Panic == nil
+						cfg.prewarm.failed(r.Err, r.Panic)
+					}
+					cfg.prewarm.wg.Done()
 				}
-				ouch <- result[Input, Output]{Panic: pval}
-			}()
-			for inval := range inch {
-				outval, err := task(inval)
-				ouch <- result[Input, Output]{inval, outval, err, nil}
 			}
-		}()
+		})
 	}
 	go func() {
 		wg.Wait()
@@ -47,3 +95,72 @@ func start[Input, Output any](n int, task Task[Input, Output]) (in chan<- Input,
 	}()
 	return inch, ouch
 }
+
+// dispatch hands the inputs received on in to the workers
+// in the order given by schedule (see WithSchedule),
+// then to each worker in turn once schedule runs out,
+// waiting for a worker to be free rather than passing it over.
+func dispatch[Input any](schedule []int, in <-chan Input, workers []chan Input) {
+	defer func() {
+		for _, ch := range workers {
+			close(ch)
+		}
+	}()
+	k := 0
+	for inval := range in {
+		w := k % len(workers)
+		if k < len(schedule) {
+			w = schedule[k]
+		}
+		workers[w] <- inval
+		k++
+	}
+}
+
+// setupWorker calls the setup set by WithWorkerSetup for worker
+// before it runs the task for in,
+// and returns a failed result for in if setup fails,
+// recovering any panic as run would.
+func setupWorker[Input, Output any](cfg *config, worker int, in Input) (r result[Input, Output]) {
+	defer func() {
+		if pval := recover(); pval != nil {
+			stack := debug.Stack()
+			if cfg.panicHandler != nil {
+				cfg.panicHandler(pval, stack)
+			}
+			r = result[Input, Output]{In: in, Panic: pval, Stack: stack}
+		}
+	}()
+	if err := cfg.workerSetup(worker); err != nil {
+		r = result[Input, Output]{In: in, Err: fmt.Errorf("workgroup: setting up worker %d: %w", worker, err)}
+	}
+	return r
+}
+
+// run executes task on a single input,
+// recovering any panic so that the worker can keep going.
+func run[Input, Output any](cfg *config, worker int, task func(int, Input) (Output, error), in Input) (r result[Input, Output]) {
+	var start time.Time
+	if cfg.observer != nil {
+		cfg.observer.started()
+		start = cfg.clock.Now()
+	}
+	defer func() {
+		if pval := recover(); pval != nil {
+			stack := debug.Stack()
+			if cfg.panicHandler != nil {
+				cfg.panicHandler(pval, stack)
+			}
+			r = result[Input, Output]{In: in, Panic: pval, Stack: stack}
+		}
+		if cfg.observer != nil {
+			err := r.Err
+			if r.Panic != nil {
+				err = panicErr(r.Panic)
+			}
+			cfg.observer.finished(cfg.clock.Now().Sub(start), err)
+		}
+	}()
+	out, err := task(worker, in)
+	return result[Input, Output]{In: in, Out: out, Err: err}
+}
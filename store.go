@@ -0,0 +1,126 @@
+package workgroup
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+const storeShards = 32
+
+// Store is a concurrency safe map for collecting results,
+// a typed alternative to sync.Map or a map guarded by a single mutex.
+// Keys are spread across internally locked shards by their hash
+// so that tasks writing different keys rarely contend.
+type Store[K comparable, V any] struct {
+	hash   func(K) uint64
+	shards [storeShards]storeShard[K, V]
+}
+
+type storeShard[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+	_  [32]byte // keep neighboring shards on separate cache lines
+}
+
+// NewStore returns an empty Store.
+// Keys that are strings or built-in integers are hashed directly.
+// Keys of any other type all share one shard,
+// so writes to them contend as they would on a single mutex;
+// use NewStoreFunc to spread them out.
+func NewStore[K comparable, V any]() *Store[K, V] {
+	return NewStoreFunc[K, V](builtinHash[K]())
+}
+
+// NewStoreFunc returns an empty Store that spreads keys across its shards
+// by hash(k), which must return the same value for keys that are equal.
+func NewStoreFunc[K comparable, V any](hash func(K) uint64) *Store[K, V] {
+	s := &Store[K, V]{hash: hash}
+	for i := range s.shards {
+		s.shards[i].m = make(map[K]V)
+	}
+	return s
+}
+
+// builtinHash returns a hash for keys of type K
+// if K is string or a built-in integer type,
+// and otherwise a hash that is the same for every key.
+func builtinHash[K comparable]() func(K) uint64 {
+	var zero K
+	switch any(zero).(type) {
+	case string:
+		seed := maphash.MakeSeed()
+		return func(k K) uint64 { return maphash.String(seed, any(k).(string)) }
+	case int:
+		return func(k K) uint64 { return uint64(any(k).(int)) }
+	case int8:
+		return func(k K) uint64 { return uint64(any(k).(int8)) }
+	case int16:
+		return func(k K) uint64 { return uint64(any(k).(int16)) }
+	case int32:
+		return func(k K) uint64 { return uint64(any(k).(int32)) }
+	case int64:
+		return func(k K) uint64 { return uint64(any(k).(int64)) }
+	case uint:
+		return func(k K) uint64 { return uint64(any(k).(uint)) }
+	case uint8:
+		return func(k K) uint64 { return uint64(any(k).(uint8)) }
+	case uint16:
+		return func(k K) uint64 { return uint64(any(k).(uint16)) }
+	case uint32:
+		return func(k K) uint64 { return uint64(any(k).(uint32)) }
+	case uint64:
+		return func(k K) uint64 { return any(k).(uint64) }
+	case uintptr:
+		return func(k K) uint64 { return uint64(any(k).(uintptr)) }
+	}
+	return func(K) uint64 { return 0 }
+}
+
+func (s *Store[K, V]) shard(k K) *storeShard[K, V] {
+	return &s.shards[s.hash(k)%storeShards]
+}
+
+// Store sets the value for k.
+func (s *Store[K, V]) Store(k K, v V) {
+	sh := s.shard(k)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.m[k] = v
+}
+
+// Load returns the value stored for k, if any.
+func (s *Store[K, V]) Load(k K) (v V, ok bool) {
+	sh := s.shard(k)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	v, ok = sh.m[k]
+	return v, ok
+}
+
+// Range calls f for each key and value in s until f returns false.
+// Like sync.Map.Range, it does not correspond to a consistent snapshot
+// if s is modified concurrently.
+// f must not call Store on s.
+func (s *Store[K, V]) Range(f func(K, V) bool) {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		for k, v := range sh.m {
+			if !f(k, v) {
+				sh.mu.RUnlock()
+				return
+			}
+		}
+		sh.mu.RUnlock()
+	}
+}
+
+// Map returns a copy of the contents of s as an ordinary map.
+func (s *Store[K, V]) Map() map[K]V {
+	m := make(map[K]V)
+	s.Range(func(k K, v V) bool {
+		m[k] = v
+		return true
+	})
+	return m
+}
@@ -0,0 +1,103 @@
+package workgroup_test
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestStore(t *testing.T) {
+	s := workgroup.NewStore[string, int]()
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+	err := workgroup.DoTasks(8, items, func(n int) error {
+		s.Store(fmt.Sprint("key", n), n)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := s.Load("key500"); !ok || v != 500 {
+		t.Fatal(v, ok)
+	}
+	if _, ok := s.Load("missing"); ok {
+		t.Fatal("loaded missing key")
+	}
+	m := s.Map()
+	if len(m) != 1000 || m["key999"] != 999 {
+		t.Fatal(len(m))
+	}
+	n := 0
+	s.Range(func(string, int) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Fatal(n)
+	}
+
+	type point struct{ x, y int }
+	ps := workgroup.NewStore[point, string]()
+	ps.Store(point{1, 2}, "a")
+	if v, _ := ps.Load(point{1, 2}); v != "a" {
+		t.Fatal(v)
+	}
+}
+
+func TestNewStoreFunc(t *testing.T) {
+	// 0.0 and -0.0 are equal keys, so they must hash alike
+	s := workgroup.NewStoreFunc[float64, string](func(f float64) uint64 {
+		if f == 0 {
+			return 0
+		}
+		return math.Float64bits(f)
+	})
+	items := make([]float64, 100)
+	for i := range items {
+		items[i] = float64(i) / 2
+	}
+	err := workgroup.DoTasks(8, items, func(f float64) error {
+		s.Store(f, fmt.Sprint(f))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := s.Load(math.Copysign(0, -1)); !ok || v != "0" {
+		t.Fatal(v, ok)
+	}
+	if m := s.Map(); len(m) != 100 || m[49.5] != "49.5" {
+		t.Fatal(len(m))
+	}
+}
+
+func BenchmarkStore(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprint("key", i)
+	}
+	b.Run("Store", func(b *testing.B) {
+		s := workgroup.NewStore[string, int]()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				s.Store(keys[i%len(keys)], i)
+			}
+		})
+	})
+	b.Run("mutex map", func(b *testing.B) {
+		var mu sync.Mutex
+		m := make(map[string]int)
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				mu.Lock()
+				m[keys[i%len(keys)]] = i
+				mu.Unlock()
+			}
+		})
+	})
+}
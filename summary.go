@@ -0,0 +1,46 @@
+package workgroup
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Summary is a report of a batch of functions run by DoFuncsSummary.
+type Summary struct {
+	Total, Succeeded, Failed int
+	Errors                   []error
+	Elapsed                  time.Duration
+}
+
+// DoFuncsSummary is like DoFuncs,
+// but it runs every function and reports the outcome of the batch
+// as a Summary instead of an error.
+// Errors are listed in the order that the functions returned them.
+// If a function panics during execution,
+// the panic will be caught and counted as a failure
+// with the panic value as its error,
+// and the other functions keep running.
+func DoFuncsSummary(n int, fns ...func() error) Summary {
+	cfg := newConfig(nil)
+	start := cfg.clock.Now()
+	s := Summary{Total: len(fns)}
+	cfg.managePanics = true
+	_ = do(context.Background(), cfg, n, func(fn func() error) (void, error) {
+		return void{}, fn()
+	}, func(_ func() error, _ void, err error) ([]func() error, error) {
+		if err == nil {
+			s.Succeeded++
+			return nil, nil
+		}
+		var pe *panicError
+		if errors.As(err, &pe) {
+			err = pe.err
+		}
+		s.Failed++
+		s.Errors = append(s.Errors, err)
+		return nil, nil
+	}, fns)
+	s.Elapsed = cfg.clock.Now().Sub(start)
+	return s
+}
@@ -0,0 +1,44 @@
+package workgroup_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoFuncsSummary(t *testing.T) {
+	errBad := errors.New("bad")
+	s := workgroup.DoFuncsSummary(2,
+		func() error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		},
+		func() error { return errBad },
+		func() error { return nil },
+	)
+	if s.Total != 3 || s.Succeeded != 2 || s.Failed != 1 {
+		t.Fatal(s)
+	}
+	if len(s.Errors) != 1 || s.Errors[0] != errBad {
+		t.Fatal(s.Errors)
+	}
+	if s.Elapsed < 10*time.Millisecond {
+		t.Fatal(s.Elapsed)
+	}
+}
+
+func TestDoFuncsSummary_panic(t *testing.T) {
+	s := workgroup.DoFuncsSummary(1,
+		func() error { return nil },
+		func() error { panic("boom") },
+		func() error { return nil },
+	)
+	if s.Total != 3 || s.Succeeded != 2 || s.Failed != 1 {
+		t.Fatal(s)
+	}
+	if len(s.Errors) != 1 || s.Errors[0].Error() != "panic: boom" {
+		t.Fatal(s.Errors)
+	}
+}
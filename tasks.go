@@ -1,6 +1,11 @@
 package workgroup
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
 
 type void = struct{}
 
@@ -10,20 +15,197 @@ type void = struct{}
 // but are joined into a multierror return value.
 // If a task panics during execution,
 // the panic will be caught and returned as an error halting further execution.
-func DoTasks[Input any](n int, items []Input, task func(Input) error) error {
+func DoTasks[Input any](n int, items []Input, task func(Input) error, opts ...Option) error {
+	return DoTasksCtx(context.Background(), n, items, task, opts...)
+}
+
+// DoTasksContext is like DoTasks, but each task is passed ctx.
+// Once ctx is done, no new tasks are dispatched,
+// and DoTasksContext returns ctx.Err()
+// (marked with ErrDeadline if the deadline of ctx passed)
+// after the tasks that are already running have finished.
+// Running tasks are not interrupted unless they observe ctx themselves.
+//
+// A task failure takes precedence over cancellation:
+// if any task failed, the task errors are returned as a multierror
+// even if ctx was also canceled, and ctx.Err() is not included.
+// Errors that halted the run for other reasons, such as ErrMaxErrors,
+// are joined with the task errors.
+// An error returned by a task once ctx is done that matches ctx.Err()
+// is treated as the task observing the cancellation rather than as a failure.
+// So errors.Is(err, context.Canceled) reports whether the run
+// was cut short by the cancellation of ctx
+// (unless a task fails with context.Canceled of its own accord).
+func DoTasksContext[Input any](ctx context.Context, n int, items []Input, task func(context.Context, Input) error, opts ...Option) error {
+	return doTasksContext(ctx, newConfig(opts), n, items, task)
+}
+
+// doTasksContext is DoTasksContext with a prepared config.
+func doTasksContext[Input any](ctx context.Context, cfg *config, n int, items []Input, task func(context.Context, Input) error) error {
 	errs := make([]error, 0, len(items))
-	err := Do(n, func(in Input) (void, error) {
-		return void{}, task(in)
+	canceled := false
+	err := doContext(ctx, cfg, n, func(ctx context.Context, in Input) (void, error) {
+		return void{}, task(ctx, in)
 	}, func(_ Input, _ void, err error) ([]Input, error) {
+		if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			canceled = true
+			return nil, nil
+		}
 		if err != nil {
 			errs = append(errs, err)
 		}
 		return nil, nil
-	}, items...)
-	if err != nil {
-		errs = append(errs, err)
+	}, items)
+	switch {
+	case len(errs) > 0:
+		if err != nil && !errors.Is(err, ctx.Err()) {
+			errs = append(errs, err)
+		}
+		return cfg.join(errs)
+	case err != nil:
+		return err
+	case canceled:
+		return ctxErr(ctx)
 	}
-	return errors.Join(errs...)
+	return nil
+}
+
+// DoTasksCtx is like DoTasks, but it stops dispatching new tasks once ctx is done
+// and returns ctx.Err() unless a task failed (see DoTasksContext).
+// Unlike DoTasksContext, the tasks themselves are not passed ctx,
+// so tasks that are already running finish normally.
+func DoTasksCtx[Input any](ctx context.Context, n int, items []Input, task func(Input) error, opts ...Option) error {
+	return DoTasksContext(ctx, n, items,
+		func(_ context.Context, in Input) error {
+			return task(in)
+		}, opts...)
+}
+
+// DoTasksErrors starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes each input as a task,
+// returning a slice of errors aligned with items.
+// errs[i] is nil if the task for items[i] succeeded.
+// Errors returned by a task do not stop the other tasks,
+// and if a task panics during execution,
+// the panic will be caught and stored as the error for its input
+// as a *PanicError.
+// If an option halts the run early, such as WithMaxErrors,
+// each input whose task did not finish
+// is given the error that halted the run, such as ErrMaxErrors.
+func DoTasksErrors[Input any](n int, items []Input, task func(Input) error, opts ...Option) []error {
+	errs := make([]error, len(items))
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	cfg := newConfig(opts)
+	cfg.managePanics = true
+	cfg.abandoned = func(in any, err error) {
+		errs[in.(int)] = err
+	}
+	viewInputs(cfg, func(i int) Input { return items[i] })
+	_ = do(context.Background(), cfg, n, func(i int) (void, error) {
+		return void{}, task(items[i])
+	}, func(i int, _ void, err error) ([]int, error) {
+		var pe *panicError
+		if errors.As(err, &pe) {
+			err = pe.err
+		}
+		errs[i] = err
+		return nil, nil
+	}, indexes)
+	return errs
+}
+
+// DoTasksIndexed starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes each input as a task,
+// returning the outputs of the tasks that succeeded
+// and the errors of the tasks that failed,
+// each keyed by the index of the input in items.
+// Every index appears in exactly one of the maps,
+// unless its input was skipped under WithDedup or WithCheckpoint,
+// so after a partial failure only the indexes in errs need to be run again.
+// If a task panics during execution,
+// the panic will be caught and stored as the error for its input.
+// If an option halts the run early, such as WithMaxErrors,
+// the indexes whose tasks did not finish are stored in errs
+// with the error that halted the run, such as ErrMaxErrors.
+func DoTasksIndexed[Input, Output any](n int, items []Input, task Task[Input, Output], opts ...Option) (outs map[int]Output, errs map[int]error) {
+	outs = make(map[int]Output, len(items))
+	errs = make(map[int]error)
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	cfg := newConfig(opts)
+	cfg.managePanics = true
+	cfg.abandoned = func(in any, err error) {
+		errs[in.(int)] = err
+	}
+	viewInputs(cfg, func(i int) Input { return items[i] })
+	_ = do(context.Background(), cfg, n, func(i int) (Output, error) {
+		return task(items[i])
+	}, func(i int, out Output, err error) ([]int, error) {
+		var pe *panicError
+		if errors.As(err, &pe) {
+			err = pe.err
+		}
+		if err != nil {
+			errs[i] = err
+		} else {
+			outs[i] = out
+		}
+		return nil, nil
+	}, indexes)
+	return outs, errs
+}
+
+// DoTasksIndexedContext is like DoTasksContext,
+// but it also returns the outputs of the tasks that succeeded,
+// keyed by the index of the input in items.
+// Once ctx is done, no new tasks are dispatched,
+// and the outputs collected so far are returned along with ctx.Err(),
+// so that a job interrupted part way through can save its progress
+// and later run only the missing indexes.
+// Tasks that are still running when ctx is done are waited for,
+// but a task that returns an error, as one that observes ctx usually does,
+// loses its output.
+// Errors are returned as by DoTasksContext.
+func DoTasksIndexedContext[Input, Output any](ctx context.Context, n int, items []Input, task func(context.Context, Input) (Output, error), opts ...Option) (outs map[int]Output, err error) {
+	outs = make(map[int]Output, len(items))
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	var errs []error
+	canceled := false
+	cfg := newConfig(opts)
+	viewInputs(cfg, func(i int) Input { return items[i] })
+	err = doContext(ctx, cfg, n, func(ctx context.Context, i int) (Output, error) {
+		return task(ctx, items[i])
+	}, func(i int, out Output, err error) ([]int, error) {
+		switch {
+		case err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()):
+			canceled = true
+		case err != nil:
+			errs = append(errs, err)
+		default:
+			outs[i] = out
+		}
+		return nil, nil
+	}, indexes)
+	switch {
+	case len(errs) > 0:
+		if err != nil && !errors.Is(err, ctx.Err()) {
+			errs = append(errs, err)
+		}
+		return outs, cfg.join(errs)
+	case err != nil:
+		return outs, err
+	case canceled:
+		return outs, ctxErr(ctx)
+	}
+	return outs, nil
 }
 
 // DoFuncs starts n concurrent workers (or GOMAXPROCS workers if n < 1)
@@ -32,8 +214,96 @@ func DoTasks[Input any](n int, items []Input, task func(Input) error) error {
 // but are joined into a multierror return value.
 // If a function panics during execution,
 // the panic will be caught and returned as an error halting further execution.
+//
+// DoFuncs can run a fixed set of operations with results of different types
+// if each function stores its result in a variable it captures,
+// such as a field of a struct being filled in.
+// Every function that was started has returned by the time DoFuncs returns,
+// even if one of them panicked,
+// so it is safe to read the variables then,
+// as long as each function writes to its own variables.
+// The variables of functions that failed or never ran may not be set.
 func DoFuncs(n int, fns ...func() error) error {
 	return DoTasks(n, fns, func(in func() error) error {
 		return in()
 	})
 }
+
+// DoFuncsResults is like DoFuncs,
+// but it also returns the result of each function in argument order.
+// The results of functions that failed or never ran are zero values.
+func DoFuncsResults[T any](n int, fns ...func() (T, error)) ([]T, error) {
+	results := make([]T, len(fns))
+	err := DoTasksInto(n, fns, results, func(fn func() (T, error)) (T, error) {
+		v, err := fn()
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		return v, nil
+	})
+	return results, err
+}
+
+// NamedFunc is a function run by DoNamedFuncs along with its name.
+type NamedFunc struct {
+	Name string
+	Func func() error
+}
+
+// DoNamedFuncs is like DoFuncs,
+// but each error is prefixed with the name of the function that returned it,
+// as in "step 'migrate-db': connection refused",
+// and the errors are joined in argument order
+// rather than in the order that the functions returned them.
+// If a function panics during execution,
+// the panic is caught and returned as its error as a *PanicError,
+// and the other functions keep running.
+func DoNamedFuncs(n int, fns ...NamedFunc) error {
+	errs := DoTasksErrors(n, fns, func(fn NamedFunc) error {
+		return fn.Func()
+	})
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = fmt.Errorf("step '%s': %w", fns[i].Name, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Fan applies each of tasks to the same input concurrently
+// with n workers (or GOMAXPROCS workers if n < 1),
+// such as looking up one record in several services at once,
+// and returns their outputs in the order of tasks.
+// Errors are joined as by DoTasks,
+// and the outputs of tasks that failed are zero values.
+func Fan[Input, Output any](n int, input Input, tasks ...func(Input) (Output, error)) ([]Output, error) {
+	fns := make([]func() (Output, error), len(tasks))
+	for i := range tasks {
+		task := tasks[i]
+		fns[i] = func() (Output, error) {
+			return task(input)
+		}
+	}
+	return DoFuncsResults(n, fns...)
+}
+
+// DoTasksRemaining is like DoTasks,
+// but each task is also passed how many inputs had yet to start
+// when it started, so that a task can change its behavior
+// near the end of a batch, such as flushing a partial buffer.
+// The last task to start is passed 0.
+// The count is kept atomically, so tasks that start at the same moment
+// may see their counts in either order.
+func DoTasksRemaining[Input any](n int, items []Input, task func(remaining int, in Input) error, opts ...Option) error {
+	var started atomic.Int64
+	return DoTasks(n, items, func(in Input) error {
+		remaining := len(items) - int(started.Add(1))
+		err := task(remaining, in)
+		if errors.Is(err, Defer) {
+			// the input will start again
+			started.Add(-1)
+		}
+		return err
+	}, opts...)
+}
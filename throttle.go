@@ -0,0 +1,68 @@
+package workgroup
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Throttle wraps task so that, across every call of the returned function,
+// at most maxConcurrent calls run task at once
+// and calls start task at a sustained rate of at most perSecond,
+// the two politeness limits most servers ask of their clients.
+// The concurrency cap also bounds bursts:
+// after a lull, up to maxConcurrent calls may start at once,
+// but the rate cap then holds later calls back
+// until the average rate is within perSecond again.
+// A call waits for a free slot first, then for its turn under the rate,
+// and if ctx is done while it waits,
+// it returns ctx.Err() without running task.
+// The returned function may be shared by any number of runs
+// to throttle them together.
+// Time is measured by clock, or by the system clock if clock is nil.
+// Throttle panics if maxConcurrent is less than 1 or perSecond is not positive.
+func Throttle[Input, Output any](clock Clock, maxConcurrent int, perSecond float64, task func(context.Context, Input) (Output, error)) func(context.Context, Input) (Output, error) {
+	if maxConcurrent < 1 {
+		panic("workgroup: Throttle called with maxConcurrent < 1")
+	}
+	if !(perSecond > 0) {
+		panic("workgroup: Throttle called with perSecond <= 0")
+	}
+	clock = orSystemClock(clock)
+	sem := make(chan void, maxConcurrent)
+	interval := time.Duration(float64(time.Second) / perSecond)
+	burst := time.Duration(maxConcurrent-1) * interval
+	var (
+		mu   sync.Mutex
+		next time.Time // when the rate would next allow a call with no burst
+	)
+	return func(ctx context.Context, in Input) (out Output, err error) {
+		if err = ctx.Err(); err != nil {
+			return out, err
+		}
+		select {
+		case sem <- void{}:
+		case <-ctx.Done():
+			return out, ctx.Err()
+		}
+		defer func() { <-sem }()
+		mu.Lock()
+		now := clock.Now()
+		if next.Before(now) {
+			next = now
+		}
+		wait := next.Sub(now) - burst
+		next = next.Add(interval)
+		mu.Unlock()
+		if wait > 0 {
+			t := clock.NewTimer(wait)
+			select {
+			case <-t.C():
+			case <-ctx.Done():
+				t.Stop()
+				return out, ctx.Err()
+			}
+		}
+		return task(ctx, in)
+	}
+}
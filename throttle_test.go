@@ -0,0 +1,60 @@
+package workgroup_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestThrottle(t *testing.T) {
+	var (
+		g      gauge
+		mu     sync.Mutex
+		starts []time.Time
+	)
+	task := workgroup.Throttle(nil, 3, 100, func(ctx context.Context, n int) (int, error) {
+		g.enter()
+		defer g.exit()
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		return n, nil
+	})
+	items := make([]int, 20)
+	outs, err := workgroup.DoTasksIndexedContext(context.Background(), 10, items, task)
+	if err != nil || len(outs) != 20 {
+		t.Fatal(err, len(outs))
+	}
+	if peak := g.peak.Load(); peak != 3 {
+		t.Fatal(peak)
+	}
+	// after a burst of 3, calls start at most every 10ms
+	first, last := starts[0], starts[0]
+	for _, s := range starts {
+		if s.Before(first) {
+			first = s
+		}
+		if s.After(last) {
+			last = s
+		}
+	}
+	if elapsed := last.Sub(first); elapsed < 165*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran := false
+	_, err = workgroup.Throttle(nil, 1, 1, func(context.Context, int) (int, error) {
+		ran = true
+		return 0, nil
+	})(ctx, 1)
+	if !errors.Is(err, context.Canceled) || ran {
+		t.Fatal(err, ran)
+	}
+}
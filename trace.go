@@ -0,0 +1,79 @@
+package workgroup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// WithTrace has a run write a line of JSON to w
+// for every step it takes, in the order it takes them,
+// to audit how a crawl evolved, such as why it ended early or looped.
+// Each line is an object with the time of the event,
+// its kind in "event", and as they apply,
+// the "input" (formatted with %v), the "worker" that ran it,
+// the task "error", and the number of inputs the manager "returned".
+// The kinds of event are:
+//
+//   - "enqueue": an input was queued
+//   - "skip": an input was not queued,
+//     because of WithDedup, WithCheckpoint, or WithCycleDetection
+//   - "drop": a queued input was discarded under WithOverflow
+//   - "dispatch": an input was handed to the workers
+//   - "start" and "finish": a worker ran the task for an input
+//   - "defer": a task returned Defer
+//   - "requeue": an input was queued again after Defer or as a retry
+//   - "manage": the manager was called with the result of a task
+//   - "halt": the run halted early, with the error that halted it
+//   - "stop": the context of the run was done
+//
+// Lines are written one at a time, so w need not be safe for concurrent use,
+// but a slow w slows the run.
+// Errors writing to w are ignored.
+// Without WithTrace, a run does none of this work.
+func WithTrace(w io.Writer) Option {
+	return func(cfg *config) {
+		cfg.trace = &tracer{enc: json.NewEncoder(w)}
+	}
+}
+
+type tracer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+type traceEvent struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	Input    string    `json:"input,omitempty"`
+	Worker   *int      `json:"worker,omitempty"`
+	Err      string    `json:"error,omitempty"`
+	Returned int       `json:"returned,omitempty"`
+}
+
+// emit writes an event for in, which may be nil, at now.
+func (t *tracer) emit(now time.Time, event string, in any, err error) {
+	t.write(traceEvent{Time: now, Event: event, Input: formatInput(in), Err: errString(err)})
+}
+
+func (t *tracer) write(e traceEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_ = t.enc.Encode(e)
+}
+
+func formatInput(in any) string {
+	if in == nil {
+		return ""
+	}
+	return fmt.Sprint(in)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
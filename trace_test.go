@@ -0,0 +1,76 @@
+package workgroup_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestWithTrace(t *testing.T) {
+	links := map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/", "/b"},
+		"/b": {"/c"},
+	}
+	var buf bytes.Buffer
+	failed := false
+	task := func(page string) ([]string, error) {
+		if page == "/c" && !failed {
+			failed = true
+			return nil, errors.New("timeout")
+		}
+		return links[page], nil
+	}
+	manager := func(page string, out []string, err error) ([]string, error) {
+		if err != nil {
+			return []string{page}, nil
+		}
+		return out, nil
+	}
+	err := workgroup.DoWith(1, task, manager, []string{"/"},
+		workgroup.WithDedup(func(page string) string { return page }),
+		workgroup.WithRetryPriority(),
+		workgroup.WithTrace(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	var last string
+	s := bufio.NewScanner(&buf)
+	for s.Scan() {
+		var e struct {
+			Event  string
+			Input  string
+			Worker *int
+		}
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		if (e.Event == "start" || e.Event == "finish") && e.Worker == nil {
+			t.Fatalf("%s without a worker", e.Event)
+		}
+		counts[e.Event]++
+		last = e.Event
+	}
+	want := map[string]int{
+		"enqueue":  4,
+		"skip":     2,
+		"dispatch": 5,
+		"start":    5,
+		"finish":   5,
+		"manage":   5,
+		"requeue":  1,
+	}
+	for event, n := range want {
+		if counts[event] != n {
+			t.Errorf("%s: got %d; want %d", event, counts[event], n)
+		}
+	}
+	if last != "manage" {
+		t.Fatal(last)
+	}
+}
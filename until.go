@@ -0,0 +1,18 @@
+package workgroup
+
+// DoTasksUntil starts n concurrent workers (or GOMAXPROCS workers if n < 1)
+// and processes each input as a task,
+// serially calling onResult with each input and the output and error of its task.
+// If onResult returns true, no further tasks are dispatched,
+// onResult is not called again, and DoTasksUntil returns nil
+// once the tasks that are already running have finished.
+// If a task panics during execution,
+// the panic will be caught and returned as an error halting further execution.
+func DoTasksUntil[Input, Output any](n int, items []Input, task Task[Input, Output], onResult func(Input, Output, error) (stop bool), opts ...Option) error {
+	return DoWith(n, task, func(in Input, out Output, err error) ([]Input, error) {
+		if onResult(in, out, err) {
+			return nil, Stop
+		}
+		return nil, nil
+	}, items, opts...)
+}
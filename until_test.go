@@ -0,0 +1,53 @@
+package workgroup_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/workgroup"
+)
+
+func TestDoTasksUntil(t *testing.T) {
+	var ran atomic.Int64
+	var results []int
+	err := workgroup.DoTasksUntil(1, []int{1, 2, 3, 4, 5, 6, 7, 8},
+		func(n int) (int, error) {
+			ran.Add(1)
+			return n * n, nil
+		}, func(n, sq int, err error) bool {
+			results = append(results, sq)
+			// stop once we find a square that is divisible by 8
+			return sq%8 == 0
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 || results[3] != 16 {
+		t.Fatal(results)
+	}
+	if n := ran.Load(); n > 5 {
+		t.Fatal(n)
+	}
+}
+
+func TestDoTasksUntil_wait(t *testing.T) {
+	var running atomic.Int64
+	err := workgroup.DoTasksUntil(4, []int{1, 2, 3, 4},
+		func(n int) (int, error) {
+			running.Add(1)
+			defer running.Add(-1)
+			if n != 1 {
+				time.Sleep(20 * time.Millisecond)
+			}
+			return n, nil
+		}, func(n, _ int, err error) bool {
+			return n == 1
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := running.Load(); n != 0 {
+		t.Fatal(n)
+	}
+}
@@ -0,0 +1,59 @@
+package workgroup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	pathpkg "path"
+)
+
+// WalkFS walks the file tree of fsys rooted at root
+// with n concurrent workers (or GOMAXPROCS workers if n < 1),
+// calling task for each file or directory in the tree, including root,
+// as fs.WalkDir would, but concurrently and in no particular order.
+// Directories are read by a single goroutine,
+// each once its own task has returned,
+// while the tasks for their entries run on the workers.
+//
+// If task returns fs.SkipDir for a directory, its contents are not walked;
+// for a file, fs.SkipDir is ignored.
+// If task returns fs.SkipAll, no more tasks are started,
+// and WalkFS returns nil once the running tasks have finished.
+// Any other error, whether returned by task or met reading a directory,
+// halts the walk, and WalkFS returns it.
+// If a task panics during execution,
+// the panic will be caught and returned as an error halting further execution.
+func WalkFS(n int, fsys fs.FS, root string, task func(path string, d fs.DirEntry) error, opts ...Option) error {
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		return err
+	}
+	type entry struct {
+		path string
+		d    fs.DirEntry
+	}
+	initial := []entry{{root, fs.FileInfoToDirEntry(info)}}
+	return do(context.Background(), newConfig(opts), n, func(e entry) (void, error) {
+		return void{}, task(e.path, e.d)
+	}, func(e entry, _ void, err error) ([]entry, error) {
+		switch {
+		case errors.Is(err, fs.SkipAll):
+			return nil, Stop
+		case errors.Is(err, fs.SkipDir):
+			return nil, nil
+		case err != nil:
+			return nil, err
+		case !e.d.IsDir():
+			return nil, nil
+		}
+		ds, err := fs.ReadDir(fsys, e.path)
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]entry, len(ds))
+		for i, d := range ds {
+			entries[i] = entry{pathpkg.Join(e.path, d.Name()), d}
+		}
+		return entries, nil
+	}, initial)
+}
@@ -0,0 +1,71 @@
+package workgroup_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/carlmjohnson/workgroup"
+	"golang.org/x/exp/slices"
+)
+
+func TestWalkFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"site/index.html":      {Data: []byte("home")},
+		"site/blog/a.html":     {Data: []byte("a")},
+		"site/blog/b.html":     {Data: []byte("b")},
+		"site/drafts/x.html":   {Data: []byte("x")},
+		"site/img/logo.png":    {Data: []byte("png")},
+		"site/img/icons/i.ico": {Data: []byte("ico")},
+	}
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+	err := workgroup.WalkFS(3, fsys, "site", func(path string, d fs.DirEntry) error {
+		if d.IsDir() && d.Name() == "drafts" {
+			return fs.SkipDir
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(seen)
+	want := "[site site/blog site/blog/a.html site/blog/b.html site/img site/img/icons site/img/icons/i.ico site/img/logo.png site/index.html]"
+	if fmt.Sprint(seen) != want {
+		t.Fatal(seen)
+	}
+}
+
+func TestWalkFS_error(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/1.txt": {}, "a/2.txt": {}, "b/3.txt": {},
+	}
+	errBad := errors.New("bad file")
+	err := workgroup.WalkFS(2, fsys, ".", func(path string, d fs.DirEntry) error {
+		if path == "a/2.txt" {
+			return errBad
+		}
+		return nil
+	})
+	if !errors.Is(err, errBad) {
+		t.Fatal(err)
+	}
+	if err := workgroup.WalkFS(2, fsys, "missing", func(string, fs.DirEntry) error {
+		return nil
+	}); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+	if err := workgroup.WalkFS(2, fsys, ".", func(string, fs.DirEntry) error {
+		return fs.SkipAll
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
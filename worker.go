@@ -0,0 +1,19 @@
+package workgroup
+
+import "context"
+
+type workerKey struct{}
+
+// WorkerID returns the index of the worker running the task
+// that was passed ctx, from 0 up to one less than the number of workers,
+// so that code deep in a task can tag its logs or traces with it
+// without the index being threaded through every call.
+// It returns -1 if ctx did not come from a worker,
+// such as a context that was not passed to a task
+// by DoContext, DoTasksContext, or another function that passes tasks a context.
+func WorkerID(ctx context.Context) int {
+	if id, ok := ctx.Value(workerKey{}).(int); ok {
+		return id
+	}
+	return -1
+}
@@ -0,0 +1,40 @@
+package workgroup_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/carlmjohnson/workgroup"
+	"golang.org/x/exp/slices"
+)
+
+func TestWorkerID(t *testing.T) {
+	if id := workgroup.WorkerID(context.Background()); id != -1 {
+		t.Fatal(id)
+	}
+	var (
+		mu      sync.Mutex
+		ids     []int
+		started sync.WaitGroup
+	)
+	started.Add(3)
+	err := workgroup.DoTasksContext(context.Background(), 3, []int{1, 2, 3},
+		func(ctx context.Context, _ int) error {
+			// hold every worker so each task runs on a different one
+			started.Done()
+			started.Wait()
+			mu.Lock()
+			ids = append(ids, workgroup.WorkerID(ctx))
+			mu.Unlock()
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(ids)
+	if fmt.Sprint(ids) != "[0 1 2]" {
+		t.Fatal(ids)
+	}
+}